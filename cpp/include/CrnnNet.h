@@ -2,8 +2,10 @@
 #define __OCR_CRNNNET_H__
 
 #include "OcrStruct.h"
+#include "CtcDecoder.h"
 #include <onnxruntime/core/session/onnxruntime_cxx_api.h>
 #include <opencv2/opencv.hpp>
+#include <unordered_map>
 
 class CrnnNet {
 public:
@@ -16,6 +18,8 @@ public:
 
     void initModel(const std::string &pathStr, const std::string &keysPath);
 
+    void setRecognizeOptions(const RecognizeOptions &options);
+
     std::vector<TextLine> getTextLines(std::vector<cv::Mat> &partImg, const char *path, const char *imgName);
 
 private:
@@ -34,6 +38,27 @@ private:
 
     std::vector<std::string> keys;
 
+    std::unordered_map<std::string, int> keyIndexes;
+
+    RecognizeOptions recognizeOptions;
+
+    //热词前缀树，只在热词变化时重建
+    HotwordTrie hotwordTrie;
+    std::vector<std::string> trieHotwords;
+
+    //keys中每个字符是否允许识别，为空时不限制
+    std::vector<bool> charMask;
+
+    std::vector<CharCandidate> topCandidates(const std::vector<float> &exps, float partition);
+
+    void buildHotwordTrie();
+
+    void buildCharMask();
+
+    void applyCharMask(std::vector<float> &exps) const;
+
+    TextLine beamSearchToTextLine(const std::vector<float> &outputData, int h, int w);
+
     TextLine scoreToTextLine(const std::vector<float> &outputData, int h, int w);
 
     TextLine getTextLine(const cv::Mat &src);
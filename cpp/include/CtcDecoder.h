@@ -0,0 +1,47 @@
+#ifndef __OCR_CTCDECODER_H__
+#define __OCR_CTCDECODER_H__
+
+#include <map>
+#include <vector>
+
+//热词前缀树，节点0为根节点，子节点按keys下标索引
+class HotwordTrie {
+public:
+    HotwordTrie();
+
+    //paths为每个热词的字符在keys中的下标（从1开始）
+    void build(const std::vector<std::vector<int>> &paths);
+
+    //沿key前进一步，完整匹配一个热词时将其字符数累加到matchedChars并回到根节点
+    void advance(int &node, int &matchedChars, int key) const;
+
+    int depth(int node) const { return depths[node]; }
+
+    bool isEnd(int node) const { return ends[node]; }
+
+private:
+    std::vector<std::map<int, int>> children;
+    std::vector<int> depths;
+    std::vector<bool> ends;
+};
+
+//CTC解码得到的字符序列
+struct CtcPath {
+    std::vector<int> keys;//每个字符在keys中的下标（从1开始）
+    std::vector<float> scores;//每个字符首次出现时的概率
+    std::vector<int> steps;//每个字符首次出现的时间步
+    //每个字符在文本行中的水平范围，为占行宽的比例
+    std::vector<float> starts;
+    std::vector<float> ends;
+};
+
+/**@fn ctcBeamSearch
+  *@brief CTC prefix beam search，包含热词的前缀按热词字符数加分
+  *@param probs h个时间步、每步w个类别的概率（已归一化），类别0为blank
+  *@param keyCount 参与解码的类别数，不超过w
+  *@param boost 热词每个字符的加分，为对数概率
+  */
+CtcPath ctcBeamSearch(const std::vector<float> &probs, int h, int w, int keyCount, int beamWidth,
+                      const HotwordTrie &trie, float boost);
+
+#endif //__OCR_CTCDECODER_H__
@@ -25,6 +25,8 @@ public:
 
     void Logger(const char *format, ...);
 
+    void setRecognizeOptions(const RecognizeOptions &options);
+
     OcrResult detect(const char *path, const char *imgName,
                      int padding, int maxSideLen,
                      float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle);
@@ -33,6 +35,19 @@ public:
                      int padding, int maxSideLen,
                      float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle);
 
+    BoxResult detectBoxes(const cv::Mat &mat,
+                          int padding, int maxSideLen,
+                          float boxScoreThresh, float boxThresh, float unClipRatio);
+
+    OcrResult detectTiled(const cv::Mat &mat, int tileSize, int tileOverlap, int padding,
+                          float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle);
+
+    OcrResult recognizeLines(const std::vector<cv::Mat> &mats, bool doAngle, bool mostAngle);
+
+    int detectPageRotation(const cv::Mat &mat, float boxScoreThresh, float boxThresh, float unClipRatio);
+
+    float detectSkewAngle(const cv::Mat &mat, float boxScoreThresh, float boxThresh, float unClipRatio);
+
 private:
     bool isOutputConsole = false;
     bool isOutputPartImg = false;
@@ -43,9 +58,15 @@ private:
     AngleNet angleNet;
     CrnnNet crnnNet;
 
+    std::vector<TextBox> probeTextBoxes(const cv::Mat &rgb, cv::Mat &paddingSrc,
+                                        float boxScoreThresh, float boxThresh, float unClipRatio);
+
     std::vector<cv::Mat> getPartImages(cv::Mat &src, std::vector<TextBox> &textBoxes,
                                        const char *path, const char *imgName);
 
+    void recognizeStacked(const std::vector<cv::Mat> &verticalImages, std::vector<TextLine> &textLines,
+                          std::vector<bool> &stacked);
+
     OcrResult detect(const char *path, const char *imgName,
                      cv::Mat &src, cv::Rect &originRect, ScaleParam &scale,
                      float boxScoreThresh = 0.6f, float boxThresh = 0.3f,
@@ -26,10 +26,32 @@ struct Angle {
     double time;
 };
 
+//候选字符及其概率
+struct CharCandidate {
+    std::string text;
+    float score;
+};
+
+//识别选项，每次识别前通过OcrLite::setRecognizeOptions设置
+struct RecognizeOptions {
+    int topK = 0;//每个字符返回的候选字符数量，0表示不返回
+    int beamWidth = 0;//CTC beam search的beam宽度，0或1表示使用贪心解码
+    std::vector<std::string> hotwords;//热词，beam search时提高包含热词的结果的得分
+    float hotwordBoost = 0;//热词每个字符的加分，为对数概率
+    std::string charWhitelist;//只识别这些字符，与charSets都为空时不限制
+    std::string charBlacklist;//不识别这些字符
+    std::vector<std::string> charSets;//只识别这些命名字符集中的字符：digits、upper、lower、alpha、alnum、hanzi、punct
+};
+
 struct TextLine {
     std::string text;
     std::vector<float> charScores;
     double time;
+    //每个字符在文本行中的水平范围，为占行宽的比例
+    std::vector<float> charStarts;
+    std::vector<float> charEnds;
+    //每个字符位置概率最高的候选字符，按概率从高到低排列
+    std::vector<std::vector<CharCandidate>> charCandidates;
 };
 
 struct OCRLITE_PORT TextBlock {
@@ -42,6 +64,9 @@ struct OCRLITE_PORT TextBlock {
     std::vector<float> charScores;
     double crnnTime;
     double blockTime;
+    std::vector<std::vector<cv::Point>> charBoxes;
+    std::vector<std::vector<CharCandidate>> charCandidates;
+    bool vertical;//竖排文本框
 };
 
 struct OCRLITE_PORT OcrResult {
@@ -50,6 +75,14 @@ struct OCRLITE_PORT OcrResult {
     cv::Mat boxImg;
     double detectTime;
     std::string strRes;
+    int pageRotation;//识别前将图片顺时针旋转的角度：0、90、180、270
+    float skewAngle;//文本行顺时针倾斜的角度，识别前已逆时针旋转该角度纠偏
+};
+
+struct OCRLITE_PORT BoxResult {
+    double dbNetTime;
+    std::vector<TextBox> textBoxes;
+    double detectTime;
 };
 
 #endif //__OCR_STRUCT_H__
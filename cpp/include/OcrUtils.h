@@ -68,8 +68,25 @@ cv::Mat matRotateClockWise180(cv::Mat src);
 
 cv::Mat matRotateClockWise90(cv::Mat src);
 
+cv::Mat rotateClockWise(const cv::Mat &src, int degrees);
+
+cv::Mat deskewImage(const cv::Mat &src, float skewAngle, cv::Mat &matrix);
+
+cv::Mat getPerspectiveCropImage(const cv::Mat &src, std::vector<cv::Point> box);
+
+cv::Mat rotateVerticalCrop(const cv::Mat &src);
+
 cv::Mat getRotateCropImage(const cv::Mat &src, std::vector<cv::Point> box);
 
+bool isVerticalImage(const cv::Mat &img);
+
+cv::Mat stackVerticalChars(const cv::Mat &src);
+
+bool isVerticalCrop(const std::vector<cv::Point> &box);
+
+std::vector<cv::Point> getCharBox(const std::vector<cv::Point> &box, float start, float end,
+                                  bool isVertical, bool isRotated180);
+
 cv::Mat adjustTargetImg(cv::Mat &src, int dstWidth, int dstHeight);
 
 std::vector<cv::Point> getMinBoxes(const std::vector<cv::Point> &inVec, float &minSideLen, float &allEdgeSize);
@@ -0,0 +1,56 @@
+#ifndef __OCR_TILING_H__
+#define __OCR_TILING_H__
+
+#include <vector>
+
+//分块识别使用的矩形，与cv::Rect一致，不包含右边和下边
+struct TileRect {
+    int x;
+    int y;
+    int width;
+    int height;
+
+    int area() const { return width * height; }
+
+    int right() const { return x + width; }
+
+    int bottom() const { return y + height; }
+
+    //交集，不相交时为空矩形
+    TileRect operator&(const TileRect &other) const;
+
+    //包含两个矩形的最小矩形
+    TileRect operator|(const TileRect &other) const;
+};
+
+//分块中检测到的文本框
+struct TileBox {
+    TileRect rect;//整图坐标
+    int tile;//分块下标
+    bool cut;//是否被分块内部的边缘截断
+    float score;
+};
+
+//合并各分块文本框的结果
+struct TileMerge {
+    std::vector<int> kept;//保留的完整文本框在boxes中的下标
+    std::vector<TileRect> lines;//被接缝截断的文本行合并后的范围，需要重新识别
+    std::vector<float> lineScores;//合并的文本框中最高的置信度
+};
+
+//一条边上每个分块的起点，相邻分块至少重叠tileOverlap并均匀分布，不超过tileSize+tileOverlap时只有一块
+std::vector<int> getTileStarts(int length, int tileSize, int tileOverlap);
+
+//按从上到下、从左到右的顺序返回所有分块，分块下标为行号*列数+列号
+std::vector<TileRect> getTiles(int width, int height, int tileSize, int tileOverlap);
+
+//box为分块坐标，是否贴近分块内部的边缘（不是整图的边缘）而被截断
+bool isCutByTile(const TileRect &tile, const TileRect &box, int width, int height);
+
+/**@fn mergeTileBoxes
+  *@brief 去掉重叠区域中重复的文本框，完整的文本框优先；被接缝截断的同一文本行的多个片段合并为一行
+  *@param width,height 整图的尺寸，合并后的文本行不超出整图
+  */
+TileMerge mergeTileBoxes(const std::vector<TileBox> &boxes, int width, int height);
+
+#endif //__OCR_TILING_H__
@@ -18,6 +18,17 @@ extern "C"
 
 const int kOcrError = 0;
 const int kOcrSuccess = 1;
+/** 识别结果的结构版本，OcrDetectResult和json结果的字段变化时必须递增，Go侧会拒绝版本不一致的结果。
+  * OcrDetectParams等输入结构的变化使用kOcrParamsVersion */
+const int kOcrResultSchemaVersion = 7;
+/** 检测参数的结构版本，OcrDetectParams的字段增删或顺序变化时必须递增，Go侧会拒绝加载版本不一致的动态库
+  * 1: padding至topK
+  * 2: 增加beamWidth、hotwords、hotwordsLen、hotwordBoost
+  * 3: 增加charWhitelist、charBlacklist、charSets
+  * 4: 增加autoRotate
+  * 5: 增加tile、tileSize、tileOverlap
+  * 6: 增加deskew、deskewMapBack */
+const int kOcrParamsVersion = 6;
 const int kDefaultPadding = 50;
 const int kDefaultMaxSideLen = 1024;
 const float kDefaultBoxScoreThresh = 0.6f;
@@ -25,6 +36,268 @@ const float kDefaultBoxThresh = 0.3f;
 const float kDefaultUnClipRatio = 2.0f;
 const bool kDefaultDoAngle = true;
 const bool kDefaultMostAngle = true;
+const float kDefaultHotwordBoost = 1.5f;
+const int kDefaultTileSize = 1024;
+const int kDefaultTileOverlap = 128;
+
+/**@brief 错误码，使用ocr_error_message获取描述 */
+typedef enum {
+    kOcrErrNone = 0,            // 成功
+    kOcrErrNotInitialized = 1,  // 引擎未初始化
+    kOcrErrFileNotFound = 2,    // 图片或模型文件不存在
+    kOcrErrDecodeImage = 3,     // 图片无法解码
+    kOcrErrBufferTooSmall = 4,  // 输出缓冲区不足
+    kOcrErrInference = 5,       // 模型加载或推理异常
+    kOcrErrInvalidArgument = 6, // 参数无效
+    kOcrErrImageTooLarge = 7,   // 图片文件或尺寸超过限制
+} OcrErrorCode;
+
+/**@brief 结果图片的编码格式 */
+typedef enum {
+    kOcrImageNone = 0, // 不返回结果图片
+    kOcrImagePng = 1,
+    kOcrImageJpeg = 2,
+} OcrImageFormat;
+
+/**@brief 检测参数，布尔值使用int以保证C与C++的结构布局一致 */
+typedef struct {
+    int padding;          // 图片四周补白的像素数
+    int maxSideLen;       // 缩放后长边的最大长度，0表示不缩放
+    float boxScoreThresh; // 文本框置信度阈值
+    float boxThresh;      // 文本区域二值化阈值
+    float unClipRatio;    // 文本框扩张比例
+    int doAngle;          // 是否进行文字方向检测
+    int mostAngle;        // 是否按多数文本块的方向统一方向
+    int resultImage;      // 结果图片格式，OcrImageFormat
+    int labelBoxes;       // 是否在结果图片上标注文本框序号和置信度
+    int charBoxes;        // 是否返回每个字符的文本框
+    int topK;             // 每个字符返回的候选字符数量，0表示不返回
+    int beamWidth;        // CTC beam search的beam宽度，0或1表示使用贪心解码
+    const char **hotwords; // 热词，UTF-8，只在beam search时生效，调用期间有效即可
+    int hotwordsLen;
+    float hotwordBoost;   // 热词每个字符的加分，为对数概率
+    const char *charWhitelist; // 只识别这些字符，UTF-8，与charSets都为空时不限制，可以为NULL
+    const char *charBlacklist; // 不识别这些字符，UTF-8，可以为NULL
+    const char *charSets;      // 逗号分隔的命名字符集：digits、upper、lower、alpha、alnum、hanzi、punct，可以为NULL
+    int autoRotate;       // 是否检测整页方向并在识别前旋转，只对整图识别生效
+    int tile;             // 是否对大图分块识别，长边超过tileSize+tileOverlap时生效，分块不缩放，maxSideLen不生效
+    int tileSize;         // 分块的边长
+    int tileOverlap;      // 相邻分块至少重叠的像素数，应大于文本行的高度，小于tileSize
+    int deskew;           // 是否检测文本行的倾斜角度并在识别前纠偏，只对整图识别生效
+    int deskewMapBack;    // 纠偏后是否将坐标和结果图片映射回纠偏前的图片
+} OcrDetectParams;
+
+/**@brief 文本框顶点 */
+typedef struct {
+    int x;
+    int y;
+} OcrPoint;
+
+/**@brief 单个字符的文本框，坐标为原图坐标 */
+typedef struct {
+    OcrPoint boxPoint[4];
+} OcrCharBox;
+
+/**@brief 候选字符 */
+typedef struct {
+    char *text;           // UTF-8，以'\0'结尾
+    float score;
+} OcrCandidate;
+
+/**@brief 单个字符位置的候选字符，按概率从高到低排列 */
+typedef struct {
+    OcrCandidate *candidates;
+    int candidatesLen;
+} OcrCharCandidates;
+
+/**@brief 文本块识别结果，字符串及数组由C侧分配 */
+typedef struct {
+    OcrPoint boxPoint[4];
+    float boxScore;
+    int angleIndex;
+    float angleScore;
+    double angleTime;
+    char *text;           // UTF-8，以'\0'结尾
+    float *charScores;
+    int charScoresLen;
+    double crnnTime;
+    double blockTime;
+    OcrCharBox *charBoxes; // 与charScores一一对应，params.charBoxes为0时为NULL
+    int charBoxesLen;
+    OcrCharCandidates *charCandidates; // 与charScores一一对应，params.topK为0时为NULL
+    int charCandidatesLen;
+    int vertical;         // 竖排文本框，识别前已旋转或逐字拼接成横排
+} OcrTextBlock;
+
+/**@brief 图片识别结果，使用ocr_free_result释放 */
+typedef struct {
+    int schemaVersion;    // kOcrResultSchemaVersion
+    double dbNetTime;
+    double detectTime;
+    OcrTextBlock *textBlocks;
+    int textBlocksLen;
+    unsigned char *image; // 标注了文本框的结果图片，按params.resultImage编码，未要求时为NULL
+    int imageLen;
+    int pageRotation;     // 识别前将图片顺时针旋转的角度：0、90、180、270，坐标和结果图片为旋转后的图片
+    float skewAngle;      // 文本行顺时针倾斜的角度，params.deskew为0时为0
+} OcrDetectResult;
+
+/**@brief 文本框检测结果，坐标为原图坐标 */
+typedef struct {
+    OcrPoint boxPoint[4];
+    float boxScore;
+} OcrTextBox;
+
+/**@brief 只检测文本框的结果，使用ocr_free_box_result释放 */
+typedef struct {
+    int schemaVersion;    // kOcrResultSchemaVersion
+    double dbNetTime;
+    double detectTime;
+    OcrTextBox *boxes;
+    int boxesLen;
+} OcrBoxResult;
+
+/**@brief 内存中的RGB图片 */
+typedef struct {
+    const unsigned char *rgb; // 按行排列的RGB像素，每像素3字节
+    int width;
+    int height;
+    int stride;               // 每行字节数，不小于width*3
+} OcrImage;
+
+/**@brief OCR实例句柄，每个实例拥有独立的模型会话，不同实例可以并发调用 */
+typedef void *OcrHandle;
+
+/**@fn ocr_create
+  *@brief 创建一个独立的OCR实例
+  *@param numThread: 实例使用的线程数量，用于该实例的onnxruntime会话；OpenMP的线程数为进程全局，只在第一次调用时设置
+  *@param dbNetPath: dbnet模型路径
+  *@param anglePath: 角度识别模型路径
+  *@param crnnPath: crnn推理模型路径
+  *@param keyPath: keys.txt样本路径
+  *@param errorCode: 输出错误码，可以为NULL
+  *@return 实例句柄，失败返回NULL
+  */
+OcrHandle ocr_create(int numThread, const char *dbNetPath, const char *anglePath, const char *crnnPath,
+                     const char *keyPath, int *errorCode);
+
+/**@fn ocr_destroy
+  *@brief 销毁ocr_create创建的实例
+  */
+void ocr_destroy(OcrHandle handle);
+
+/**@fn ocr_detect_handle
+  *@brief 使用指定实例识别图片，同一实例不能并发调用
+  *@param handle: ocr_create返回的实例
+  *@param 其余参数同ocr_detect
+  *@return 成功与否，失败原因使用ocr_last_error获取
+  */
+int ocr_detect_handle(OcrHandle handle, const char *image_path, char *out_buffer, int *buffer_len, int padding,
+                      int maxSideLen, float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle,
+                      bool mostAngle);
+
+/**@fn ocr_default_params
+  *@brief 返回默认检测参数
+  */
+OcrDetectParams ocr_default_params();
+
+/**@fn ocr_detect_result
+  *@brief 使用指定实例识别图片，结果大小不受缓冲区限制
+  *@param handle: ocr_create返回的实例
+  *@param image_path: 图片完整路径
+  *@param params: 检测参数，NULL表示使用默认参数
+  *@param errorCode: 输出错误码，可以为NULL
+  *@return 识别结果，失败返回NULL，使用后必须调用ocr_free_result释放
+  */
+OcrDetectResult *ocr_detect_result(OcrHandle handle, const char *image_path, const OcrDetectParams *params,
+                                   int *errorCode);
+
+/**@fn ocr_detect_buffer
+  *@brief 识别内存中已编码的图片（jpg、png等），不读写磁盘
+  *@param handle: ocr_create返回的实例
+  *@param data: 编码后的图片数据
+  *@param dataLen: 数据长度
+  *@param params: 检测参数，NULL表示使用默认参数
+  *@param errorCode: 输出错误码，可以为NULL
+  *@return 识别结果，失败返回NULL，使用后必须调用ocr_free_result释放
+  */
+OcrDetectResult *ocr_detect_buffer(OcrHandle handle, const unsigned char *data, int dataLen,
+                                   const OcrDetectParams *params, int *errorCode);
+
+/**@fn ocr_detect_pixels
+  *@brief 识别内存中的RGB像素数据，不读写磁盘
+  *@param handle: ocr_create返回的实例
+  *@param rgb: 按行排列的RGB像素，每像素3字节
+  *@param width: 图片宽度
+  *@param height: 图片高度
+  *@param stride: 每行字节数，不小于width*3
+  *@param params: 检测参数，NULL表示使用默认参数
+  *@param errorCode: 输出错误码，可以为NULL
+  *@return 识别结果，失败返回NULL，使用后必须调用ocr_free_result释放
+  */
+OcrDetectResult *ocr_detect_pixels(OcrHandle handle, const unsigned char *rgb, int width, int height, int stride,
+                                   const OcrDetectParams *params, int *errorCode);
+
+/**@fn ocr_free_result
+  *@brief 释放ocr_detect_result返回的结果
+  */
+void ocr_free_result(OcrDetectResult *result);
+
+/**@fn ocr_detect_boxes_buffer
+  *@brief 只运行DbNet检测内存中已编码图片的文本框，不进行方向分类和文字识别
+  *@param handle: ocr_create返回的实例
+  *@param data: 编码后的图片数据
+  *@param dataLen: 数据长度
+  *@param params: 检测参数，NULL表示使用默认参数，doAngle和mostAngle不生效
+  *@param errorCode: 输出错误码，可以为NULL
+  *@return 检测结果，失败返回NULL，使用后必须调用ocr_free_box_result释放
+  */
+OcrBoxResult *ocr_detect_boxes_buffer(OcrHandle handle, const unsigned char *data, int dataLen,
+                                      const OcrDetectParams *params, int *errorCode);
+
+/**@fn ocr_detect_boxes_pixels
+  *@brief 只运行DbNet检测内存中RGB像素数据的文本框，参数同ocr_detect_pixels
+  *@return 检测结果，失败返回NULL，使用后必须调用ocr_free_box_result释放
+  */
+OcrBoxResult *ocr_detect_boxes_pixels(OcrHandle handle, const unsigned char *rgb, int width, int height, int stride,
+                                      const OcrDetectParams *params, int *errorCode);
+
+/**@fn ocr_free_box_result
+  *@brief 释放ocr_detect_boxes_*返回的结果
+  */
+void ocr_free_box_result(OcrBoxResult *result);
+
+/**@fn ocr_recognize_lines
+  *@brief 跳过DbNet，将每张图片作为一个文本行直接识别，适合已经裁剪好的文本行
+  *@param handle: ocr_create返回的实例
+  *@param images: 文本行图片
+  *@param imagesLen: 图片数量
+  *@param params: 检测参数，只使用doAngle和mostAngle，NULL表示使用默认参数
+  *@param errorCode: 输出错误码，可以为NULL
+  *@return 识别结果，textBlocks与images一一对应，boxPoint为整张图片，失败返回NULL，使用后必须调用ocr_free_result释放
+  */
+OcrDetectResult *ocr_recognize_lines(OcrHandle handle, const OcrImage *images, int imagesLen,
+                                     const OcrDetectParams *params, int *errorCode);
+
+/**@fn ocr_error_message
+  *@brief 返回错误码对应的描述，返回的字符串为静态常量，无需释放
+  */
+const char *ocr_error_message(int code);
+
+/**@fn ocr_last_error
+  *@brief 返回当前线程最近一次调用的错误码，用于ocr_init、ocr_detect等只返回成功与否的函数
+  */
+int ocr_last_error();
+
+/**@fn ocr_schema_version
+  *@brief 返回库编译时的结果结构版本，用于检查头文件与动态库是否一致
+  */
+int ocr_schema_version();
+
+/**@fn ocr_params_version
+  *@brief 返回库编译时的检测参数结构版本，用于检查调用方的OcrDetectParams布局与动态库是否一致
+  */
+int ocr_params_version();
 
 /**@fn ocr_init
   *@brief 初始化OCR
@@ -33,7 +306,7 @@ const bool kDefaultMostAngle = true;
   *@param anglePath: 角度识别模型路径
   *@param crnnPath: crnn推理模型路径
   *@param keyPath: keys.txt样本路径
-  *@return <0: error, >0: instance
+  *@return 成功与否，失败原因使用ocr_last_error获取
   */
 int ocr_init(int numThread, const char *dbNetPath, const char *anglePath, const char *crnnPath, const char *keyPath);
 
@@ -45,8 +318,11 @@ void ocr_cleanup();
 /**@fn ocr_detect
   *@brief 识别图片
   *@param image_path: 图片完整路径，会在同路径下生成图片识别框选效果，便于调试
-  *@param out_json_result: 识别结果输出，json格式。
-  *@param buffer_len: 输出缓冲区大小
+  *@param out_json_result: 识别结果输出，json格式，字段为snake_case，
+  *       包含schema_version、db_net_time、detect_time、page_rotation、skew_angle、texts以及text_blocks数组，
+  *       text_blocks元素包含box_point、box_score、angle_index、angle_score、angle_time、
+  *       text、char_scores、crnn_time、block_time、vertical
+  *@param buffer_len: 输入为输出缓冲区大小，输出为结果长度；缓冲区不足时返回失败并写入所需大小
   *@param padding: 50
   *@param maxSideLen: 1024
   *@param boxScoreThresh: 0.6f
@@ -54,7 +330,7 @@ void ocr_cleanup();
   *@param unClipRatio: 2.0f
   *@param doAngle: true
   *@param mostAngle: true
-  *@return 成功与否
+  *@return 成功与否，失败原因使用ocr_last_error获取
   */
 int ocr_detect(const char *image_path, char *out_buffer, int *buffer_len, int padding, int maxSideLen,
                 float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle);
@@ -1,6 +1,9 @@
 #include "CrnnNet.h"
 #include "OcrUtils.h"
 #include <fstream>
+#include <algorithm>
+#include <cctype>
+#include <cmath>
 #include <numeric>
 
 CrnnNet::CrnnNet() {}
@@ -16,8 +19,8 @@ void CrnnNet::setNumThread(int numOfThread) {
     //===session options===
     // Sets the number of threads used to parallelize the execution within nodes
     // A value of 0 means ORT will pick a default
-    //sessionOptions.SetIntraOpNumThreads(numThread);
-    //set OMP_NUM_THREADS=16
+    // 每个实例的会话使用自己的线程数，OpenMP的线程数是进程全局的，只在ocr_create第一次调用时设置
+    sessionOptions.SetIntraOpNumThreads(numThread);
 
     // Sets the number of threads used to parallelize the execution of the graph (across nodes)
     // If sequential execution is enabled this value is ignored
@@ -53,6 +56,9 @@ void CrnnNet::initModel(const std::string &pathStr, const std::string &keysPath)
         printf("The keys.txt file was not found\n");
         return;
     }
+    for (int j = 0; j < int(keys.size()); j++) {
+        keyIndexes.emplace(keys[j], j + 1);
+    }
     if (keys.size() != 5531) {
         fprintf(stderr, "missing keys\n");
     }
@@ -60,15 +66,150 @@ void CrnnNet::initModel(const std::string &pathStr, const std::string &keysPath)
 
 }
 
+void CrnnNet::setRecognizeOptions(const RecognizeOptions &options) {
+    recognizeOptions = options;
+    buildCharMask();
+    //the trie only depends on the hotwords, most requests reuse the same list
+    if (recognizeOptions.hotwords != trieHotwords) {
+        buildHotwordTrie();
+    }
+}
+
+//split utf-8 string into chars by the lead byte
+static std::vector<std::string> splitUtf8(const std::string &str) {
+    std::vector<std::string> chars;
+    for (size_t i = 0; i < str.size();) {
+        unsigned char lead = str[i];
+        size_t len = lead < 0x80 ? 1 : lead < 0xE0 ? 2 : lead < 0xF0 ? 3 : 4;
+        chars.emplace_back(str.substr(i, len));
+        i += len;
+    }
+    return chars;
+}
+
+static unsigned int utf8CodePoint(const std::string &ch) {
+    if (ch.empty()) {
+        return 0;
+    }
+    unsigned char lead = ch[0];
+    if (lead < 0x80) {
+        return lead;
+    }
+    int len = lead < 0xE0 ? 2 : lead < 0xF0 ? 3 : 4;
+    unsigned int codePoint = lead & (0xFF >> (len + 1));
+    for (int i = 1; i < len && i < int(ch.size()); i++) {
+        codePoint = (codePoint << 6) | (ch[i] & 0x3F);
+    }
+    return codePoint;
+}
+
+//whether a key belongs to the named char set, unknown names match nothing
+static bool inCharSet(const std::string &name, const std::string &key) {
+    unsigned int c = utf8CodePoint(key);
+    bool digit = c >= '0' && c <= '9';
+    bool upper = c >= 'A' && c <= 'Z';
+    bool lower = c >= 'a' && c <= 'z';
+    if (name == "digits") {
+        return digit;
+    } else if (name == "upper") {
+        return upper;
+    } else if (name == "lower") {
+        return lower;
+    } else if (name == "alpha") {
+        return upper || lower;
+    } else if (name == "alnum") {
+        return digit || upper || lower;
+    } else if (name == "hanzi") {
+        return (c >= 0x4E00 && c <= 0x9FFF) || (c >= 0x3400 && c <= 0x4DBF);
+    } else if (name == "punct") {
+        return (c < 0x80 && ispunct(int(c))) || (c >= 0x3000 && c <= 0x303F) ||
+               (c >= 0xFF01 && c <= 0xFF0F) || (c >= 0xFF1A && c <= 0xFF20) ||
+               (c >= 0xFF3B && c <= 0xFF40) || (c >= 0xFF5B && c <= 0xFF65);
+    }
+    return false;
+}
+
+void CrnnNet::buildCharMask() {
+    const RecognizeOptions &options = recognizeOptions;
+    charMask.clear();
+    if (options.charWhitelist.empty() && options.charSets.empty() && options.charBlacklist.empty()) {
+        return;
+    }
+    bool restricted = !options.charWhitelist.empty() || !options.charSets.empty();
+    charMask.assign(keys.size(), !restricted);
+    for (const auto &ch : splitUtf8(options.charWhitelist)) {
+        auto it = keyIndexes.find(ch);
+        if (it != keyIndexes.end()) {
+            charMask[it->second - 1] = true;
+        }
+    }
+    for (const auto &name : options.charSets) {
+        for (int j = 0; j < int(keys.size()); j++) {
+            if (inCharSet(name, keys[j])) {
+                charMask[j] = true;
+            }
+        }
+    }
+    for (const auto &ch : splitUtf8(options.charBlacklist)) {
+        auto it = keyIndexes.find(ch);
+        if (it != keyIndexes.end()) {
+            charMask[it->second - 1] = false;
+        }
+    }
+}
+
+//zero the disallowed chars before softmax normalization, the ctc blank is always allowed
+void CrnnNet::applyCharMask(std::vector<float> &exps) const {
+    if (charMask.empty()) {
+        return;
+    }
+    for (int j = 1; j < int(exps.size()); j++) {
+        if (j > int(charMask.size()) || !charMask[j - 1]) {
+            exps[j] = 0;
+        }
+    }
+}
+
 template<class ForwardIterator>
 inline static size_t argmax(ForwardIterator first, ForwardIterator last) {
     return std::distance(first, std::max_element(first, last));
 }
 
+std::vector<CharCandidate> CrnnNet::topCandidates(const std::vector<float> &exps, float partition) {
+    //index 0 is the ctc blank, key j is keys[j - 1]
+    int keyCount = (std::min)(int(exps.size()), int(keys.size()) + 1);
+    std::vector<int> indexes;
+    for (int j = 1; j < keyCount; j++) {
+        indexes.emplace_back(j);
+    }
+    int k = (std::min)(recognizeOptions.topK, int(indexes.size()));
+    std::partial_sort(indexes.begin(), indexes.begin() + k, indexes.end(),
+                      [&exps](int a, int b) { return exps[a] > exps[b]; });
+    std::vector<CharCandidate> candidates;
+    for (int j = 0; j < k && exps[indexes[j]] > 0; j++) {
+        candidates.push_back(CharCandidate{keys[indexes[j] - 1], exps[indexes[j]] / partition});
+    }
+    return candidates;
+}
+
+//split the gap between adjacent chars at its middle, so char boxes are contiguous
+static void splitCharGaps(std::vector<float> &starts, std::vector<float> &ends) {
+    for (int i = 0; i + 1 < int(starts.size()); i++) {
+        float middle = (ends[i] + starts[i + 1]) / 2;
+        ends[i] = middle;
+        starts[i + 1] = middle;
+    }
+}
+
 TextLine CrnnNet::scoreToTextLine(const std::vector<float> &outputData, int h, int w) {
+    if (recognizeOptions.beamWidth > 1) {
+        return beamSearchToTextLine(outputData, h, w);
+    }
     int keySize = keys.size();
     std::string strRes;
     std::vector<float> scores;
+    std::vector<float> starts, ends;
+    std::vector<std::vector<CharCandidate>> candidates;
     int lastIndex = 0;
     int maxIndex;
     float maxValue;
@@ -82,16 +223,79 @@ TextLine CrnnNet::scoreToTextLine(const std::vector<float> &outputData, int h, i
             float expSingle = exp(outputData[i * w + j]);
             exps.at(j) = expSingle;
         }
+        applyCharMask(exps);
         float partition = accumulate(exps.begin(), exps.end(), 0.0);//row sum
         maxIndex = int(argmax(exps.begin(), exps.end()));
         maxValue = float(*std::max_element(exps.begin(), exps.end())) / partition;
         if (maxIndex > 0 && maxIndex < keySize && (!(i > 0 && maxIndex == lastIndex))) {
             scores.emplace_back(maxValue);
             strRes.append(keys[maxIndex - 1]);
+            starts.emplace_back(float(i) / h);
+            ends.emplace_back(float(i + 1) / h);
+            if (recognizeOptions.topK > 0) {
+                candidates.emplace_back(topCandidates(exps, partition));
+            }
+        } else if (maxIndex > 0 && maxIndex < keySize && !ends.empty()) {
+            //same char spans several timesteps
+            ends.back() = float(i + 1) / h;
         }
         lastIndex = maxIndex;
     }
-    return {strRes, scores};
+    splitCharGaps(starts, ends);
+    return {strRes, scores, 0, starts, ends, candidates};
+}
+
+void CrnnNet::buildHotwordTrie() {
+    std::vector<std::vector<int>> paths;
+    for (const auto &hotword : recognizeOptions.hotwords) {
+        std::vector<int> path;
+        for (const auto &ch : splitUtf8(hotword)) {
+            auto it = keyIndexes.find(ch);
+            if (it == keyIndexes.end()) {
+                path.clear();
+                break;
+            }
+            path.emplace_back(it->second);
+        }
+        //hotwords containing chars outside keys can never be decoded
+        if (!path.empty()) {
+            paths.emplace_back(path);
+        }
+    }
+    hotwordTrie.build(paths);
+    trieHotwords = recognizeOptions.hotwords;
+}
+
+TextLine CrnnNet::beamSearchToTextLine(const std::vector<float> &outputData, int h, int w) {
+    int keyCount = (std::min)(w, int(keys.size()) + 1);
+    std::vector<float> probs(outputData.begin(), outputData.begin() + h * w);
+    for (int i = 0; i < h; i++) {
+        //do softmax
+        std::vector<float> exps(w);
+        for (int j = 0; j < w; j++) {
+            exps[j] = exp(outputData[i * w + j]);
+        }
+        applyCharMask(exps);
+        float partition = accumulate(exps.begin(), exps.end(), 0.0);//row sum
+        for (int j = 0; j < w; j++) {
+            probs[i * w + j] = exps[j] / partition;
+        }
+    }
+
+    CtcPath path = ctcBeamSearch(probs, h, w, keyCount, recognizeOptions.beamWidth, hotwordTrie,
+                                 recognizeOptions.hotwordBoost);
+    std::string strRes;
+    std::vector<std::vector<CharCandidate>> candidates;
+    for (int k = 0; k < int(path.keys.size()); k++) {
+        strRes.append(keys[path.keys[k] - 1]);
+        if (recognizeOptions.topK > 0) {
+            int step = path.steps[k];
+            candidates.emplace_back(topCandidates(
+                    std::vector<float>(probs.begin() + step * w, probs.begin() + (step + 1) * w), 1.0f));
+        }
+    }
+    splitCharGaps(path.starts, path.ends);
+    return {strRes, path.scores, 0, path.starts, path.ends, candidates};
 }
 
 TextLine CrnnNet::getTextLine(const cv::Mat &src) {
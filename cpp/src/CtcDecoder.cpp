@@ -0,0 +1,168 @@
+#include "CtcDecoder.h"
+#include <algorithm>
+#include <cmath>
+#include <limits>
+
+HotwordTrie::HotwordTrie() : children(1), depths(1, 0), ends(1, false) {}
+
+void HotwordTrie::build(const std::vector<std::vector<int>> &paths) {
+    children.assign(1, std::map<int, int>());
+    depths.assign(1, 0);
+    ends.assign(1, false);
+    for (const auto &path : paths) {
+        if (path.empty()) {
+            continue;
+        }
+        int node = 0;
+        for (int key : path) {
+            auto child = children[node].find(key);
+            if (child == children[node].end()) {
+                children.emplace_back();
+                depths.emplace_back(depths[node] + 1);
+                ends.emplace_back(false);
+                child = children[node].emplace(key, int(children.size()) - 1).first;
+            }
+            node = child->second;
+        }
+        ends[node] = true;
+    }
+}
+
+void HotwordTrie::advance(int &node, int &matchedChars, int key) const {
+    auto child = children[node].find(key);
+    if (child == children[node].end()) {
+        //the partial match is broken, keep the credit only if it is a complete hotword
+        if (ends[node]) {
+            matchedChars += depths[node];
+        }
+        node = 0;
+        child = children[0].find(key);
+        if (child == children[0].end()) {
+            return;
+        }
+    }
+    node = child->second;
+    if (ends[node] && children[node].empty()) {
+        matchedChars += depths[node];
+        node = 0;
+    }
+}
+
+static const float kLogZero = -std::numeric_limits<float>::infinity();
+
+inline static float logAdd(float a, float b) {
+    if (a == kLogZero) {
+        return b;
+    }
+    if (b == kLogZero) {
+        return a;
+    }
+    float m = (std::max)(a, b);
+    return m + std::log(std::exp(a - m) + std::exp(b - m));
+}
+
+//ctc prefix beam search中的一个候选前缀
+struct BeamEntry {
+    CtcPath path;
+    float blankLogProb = kLogZero;//ending with blank
+    float charLogProb = kLogZero;//ending with the last char of prefix
+    int hotwordNode = 0;//current node in the hotword trie
+    int hotwordChars = 0;//chars of completely matched hotwords
+
+    float logProb() const {
+        return logAdd(blankLogProb, charLogProb);
+    }
+};
+
+CtcPath ctcBeamSearch(const std::vector<float> &probs, int h, int w, int keyCount, int beamWidth,
+                      const HotwordTrie &trie, float boost) {
+    //only the most probable chars of each timestep are used to extend prefixes
+    int charCount = (std::max)(0, (std::min)(keyCount - 1, (std::max)(beamWidth, 10)));
+    //partial hotword matches get the boost too, so they survive pruning until completed
+    auto rankScore = [&trie, boost](const BeamEntry &entry) {
+        return entry.logProb() + boost * float(entry.hotwordChars + trie.depth(entry.hotwordNode));
+    };
+
+    std::vector<BeamEntry> beams(1);
+    beams[0].blankLogProb = 0;
+    for (int i = 0; i < h; i++) {
+        const float *step = probs.data() + i * w;
+        std::vector<int> chars;
+        for (int j = 1; j < keyCount; j++) {
+            chars.emplace_back(j);
+        }
+        std::partial_sort(chars.begin(), chars.begin() + charCount, chars.end(),
+                          [step](int a, int b) { return step[a] > step[b]; });
+        chars.resize(charCount);
+
+        //prefix unchanged: blank, or the last char repeated. Beams have distinct prefixes, so every
+        //unchanged entry is created from its own beam before any extension can reach the same prefix
+        std::map<std::vector<int>, BeamEntry> next;
+        for (const auto &beam : beams) {
+            BeamEntry entry = beam;
+            entry.blankLogProb = beam.logProb() + std::log(step[0]);
+            entry.charLogProb = kLogZero;
+            int last = beam.path.keys.empty() ? 0 : beam.path.keys.back();
+            if (last > 0) {
+                entry.charLogProb = beam.charLogProb + std::log(step[last]);
+                //the char spans this timestep only when it is more likely than blank, as in greedy decoding
+                if (step[last] > step[0]) {
+                    entry.path.ends.back() = float(i + 1) / h;
+                }
+            }
+            next.emplace(beam.path.keys, entry);
+        }
+
+        //prefix extended with a new char, a repeated char must be separated by blank
+        for (const auto &beam : beams) {
+            float total = beam.logProb();
+            int last = beam.path.keys.empty() ? 0 : beam.path.keys.back();
+            for (int c : chars) {
+                float from = c == last ? beam.blankLogProb : total;
+                if (from == kLogZero || step[c] <= 0) {
+                    continue;
+                }
+                std::vector<int> prefix = beam.path.keys;
+                prefix.emplace_back(c);
+                auto extended = next.find(prefix);
+                if (extended == next.end()) {
+                    BeamEntry entry = beam;
+                    entry.path.keys = prefix;
+                    entry.blankLogProb = kLogZero;
+                    entry.charLogProb = kLogZero;
+                    entry.path.scores.emplace_back(step[c]);
+                    entry.path.steps.emplace_back(i);
+                    entry.path.starts.emplace_back(float(i) / h);
+                    entry.path.ends.emplace_back(float(i + 1) / h);
+                    trie.advance(entry.hotwordNode, entry.hotwordChars, c);
+                    extended = next.emplace(prefix, entry).first;
+                }
+                //an existing entry keeps the scores and positions of the beam that already had this prefix
+                extended->second.charLogProb = logAdd(extended->second.charLogProb, from + std::log(step[c]));
+            }
+        }
+
+        beams.clear();
+        for (auto &item : next) {
+            beams.emplace_back(std::move(item.second));
+        }
+        int keep = (std::min)(beamWidth, int(beams.size()));
+        std::partial_sort(beams.begin(), beams.begin() + keep, beams.end(),
+                          [&rankScore](const BeamEntry &a, const BeamEntry &b) { return rankScore(a) > rankScore(b); });
+        beams.resize(keep);
+    }
+
+    //only completed hotwords count for the final result
+    auto finalScore = [&trie, boost](const BeamEntry &entry) {
+        int matchedChars = entry.hotwordChars;
+        if (trie.isEnd(entry.hotwordNode)) {
+            matchedChars += trie.depth(entry.hotwordNode);
+        }
+        return entry.logProb() + boost * float(matchedChars);
+    };
+    const BeamEntry &best = *std::max_element(beams.begin(), beams.end(),
+                                              [&finalScore](const BeamEntry &a, const BeamEntry &b) {
+                                                  return finalScore(a) < finalScore(b);
+                                              });
+    return best.path;
+}
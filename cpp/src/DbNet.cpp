@@ -14,8 +14,8 @@ void DbNet::setNumThread(int numOfThread) {
     //===session options===
     // Sets the number of threads used to parallelize the execution within nodes
     // A value of 0 means ORT will pick a default
-    //sessionOptions.SetIntraOpNumThreads(numThread);
-    //set OMP_NUM_THREADS=16
+    // 每个实例的会话使用自己的线程数，OpenMP的线程数是进程全局的，只在ocr_create第一次调用时设置
+    sessionOptions.SetIntraOpNumThreads(numThread);
 
     // Sets the number of threads used to parallelize the execution of the graph (across nodes)
     // If sequential execution is enabled this value is ignored
@@ -1,5 +1,9 @@
 #include "OcrLite.h"
 #include "OcrUtils.h"
+#include "Tiling.h"
+#include <algorithm>
+#include <cmath>
+#include <numeric>
 #include <stdarg.h> //windows&linux
 
 OcrLite::OcrLite() {}
@@ -16,6 +20,10 @@ void OcrLite::setNumThread(int numOfThread) {
     crnnNet.setNumThread(numOfThread);
 }
 
+void OcrLite::setRecognizeOptions(const RecognizeOptions &options) {
+    crnnNet.setRecognizeOptions(options);
+}
+
 void OcrLite::initLogger(bool isConsole, bool isPartImg, bool isResultImg) {
     isOutputConsole = isConsole;
     isOutputPartImg = isPartImg;
@@ -112,6 +120,359 @@ OcrResult OcrLite::detect(const cv::Mat& mat, int padding, int maxSideLen, float
     return result;
 }
 
+BoxResult OcrLite::detectBoxes(const cv::Mat &mat, int padding, int maxSideLen,
+                               float boxScoreThresh, float boxThresh, float unClipRatio) {
+    cv::Mat originSrc;
+    cvtColor(mat, originSrc, cv::COLOR_BGR2RGB);// convert to RGB
+    int originMaxSide = (std::max)(originSrc.cols, originSrc.rows);
+    int resize;
+    if (maxSideLen <= 0 || maxSideLen > originMaxSide) {
+        resize = originMaxSide;
+    } else {
+        resize = maxSideLen;
+    }
+    resize += 2 * padding;
+    cv::Mat paddingSrc = makePadding(originSrc, padding);
+    ScaleParam scale = getScaleParam(paddingSrc, resize);
+
+    Logger("---------- step: dbNet getTextBoxes only ----------\n");
+    double startTime = getCurrentTime();
+    std::vector<TextBox> textBoxes = dbNet.getTextBoxes(paddingSrc, scale, boxScoreThresh, boxThresh, unClipRatio);
+    double dbNetTime = getCurrentTime() - startTime;
+    Logger("dbNetTime(%fms)\n", dbNetTime);
+
+    //padding conversion
+    for (auto &textBox : textBoxes) {
+        for (auto &point : textBox.boxPoint) {
+            point.x -= padding;
+            point.y -= padding;
+        }
+    }
+
+    double fullTime = getCurrentTime() - startTime;
+    return BoxResult{dbNetTime, textBoxes, fullTime};
+}
+
+//a small detection pass over a downscaled rgb image, boxes are in paddingSrc coordinates
+std::vector<TextBox> OcrLite::probeTextBoxes(const cv::Mat &rgb, cv::Mat &paddingSrc,
+                                             float boxScoreThresh, float boxThresh, float unClipRatio) {
+    const int probeSideLen = 960;
+    const int probePadding = 32;
+    cv::Mat src = rgb;
+    paddingSrc = makePadding(src, probePadding);
+    int resize = (std::min)(probeSideLen, (std::max)(rgb.cols, rgb.rows)) + 2 * probePadding;
+    ScaleParam scale = getScaleParam(paddingSrc, resize);
+    return dbNet.getTextBoxes(paddingSrc, scale, boxScoreThresh, boxThresh, unClipRatio);
+}
+
+//find the page orientation with a small detection pass, returns the clockwise degrees that turn mat upright:
+//the lengths of horizontal and vertical boxes tell 0/180 from 90/270, angleNet on the longest lines tells 180
+int OcrLite::detectPageRotation(const cv::Mat &mat, float boxScoreThresh, float boxThresh, float unClipRatio) {
+    const int maxAngleSamples = 16;
+    double startTime = getCurrentTime();
+    cv::Mat rgb;
+    cvtColor(mat, rgb, cv::COLOR_BGR2RGB);// convert to RGB
+
+    Logger("---------- step: page rotation ----------\n");
+    cv::Mat paddingSrc;
+    std::vector<TextBox> textBoxes;
+    auto probe = [&](const cv::Mat &img) {
+        textBoxes = probeTextBoxes(img, paddingSrc, boxScoreThresh, boxThresh, unClipRatio);
+    };
+    auto boxSize = [](const TextBox &box, double &width, double &height) {
+        width = cv::norm(box.boxPoint[0] - box.boxPoint[1]);
+        height = cv::norm(box.boxPoint[0] - box.boxPoint[3]);
+    };
+
+    probe(rgb);
+    double horizontal = 0, vertical = 0;
+    for (const auto &box : textBoxes) {
+        double width, height;
+        boxSize(box, width, height);
+        if (width >= height * 1.5) {
+            horizontal += width;
+        } else if (height >= width * 1.5) {
+            vertical += height;
+        }
+    }
+    //pages of vertical chinese also have tall boxes, only turn the page when they clearly dominate
+    int rotation = vertical > horizontal * 2 ? 90 : 0;
+    if (rotation != 0) {
+        probe(rotateClockWise(rgb, rotation));
+    }
+
+    std::vector<TextBox> lines;
+    for (const auto &box : textBoxes) {
+        double width, height;
+        boxSize(box, width, height);
+        if (width >= height * 1.5) {
+            lines.emplace_back(box);
+        }
+    }
+    std::sort(lines.begin(), lines.end(), [&boxSize](const TextBox &a, const TextBox &b) {
+        double aWidth, aHeight, bWidth, bHeight;
+        boxSize(a, aWidth, aHeight);
+        boxSize(b, bWidth, bHeight);
+        return aWidth > bWidth;
+    });
+    if (int(lines.size()) > maxAngleSamples) {
+        lines.resize(maxAngleSamples);
+    }
+    std::vector<cv::Mat> partImages = getPartImages(paddingSrc, lines, NULL, NULL);
+    std::vector<Angle> angles = angleNet.getAngles(partImages, NULL, NULL, true, false);
+    double upright = 0, upsideDown = 0;
+    for (const auto &angle : angles) {
+        if (angle.index == 0) {
+            upsideDown += angle.score;
+        } else {
+            upright += angle.score;
+        }
+    }
+    if (upsideDown > upright) {
+        rotation += 180;
+    }
+    Logger("pageRotation(%d), horizontal(%f), vertical(%f), upright(%f), upsideDown(%f), time(%fms)\n", rotation,
+           horizontal, vertical, upright, upsideDown, getCurrentTime() - startTime);
+    return rotation;
+}
+
+//estimate the skew of the text lines with a small detection pass, returns the clockwise degrees the lines are
+//tilted by: the length weighted median angle of the top edges of long boxes, 0 when there are too few lines
+float OcrLite::detectSkewAngle(const cv::Mat &mat, float boxScoreThresh, float boxThresh, float unClipRatio) {
+    const float maxSkew = 15.0f;//larger angles are text set at an angle, not a skewed scan
+    const float minSkew = 0.2f;//not worth resampling the image
+    const int minLines = 3;
+    double startTime = getCurrentTime();
+    cv::Mat rgb, paddingSrc;
+    cvtColor(mat, rgb, cv::COLOR_BGR2RGB);// convert to RGB
+
+    Logger("---------- step: skew angle ----------\n");
+    std::vector<TextBox> textBoxes = probeTextBoxes(rgb, paddingSrc, boxScoreThresh, boxThresh, unClipRatio);
+    std::vector<std::pair<float, double>> angles;//angle, length
+    double totalLength = 0;
+    for (const auto &box : textBoxes) {
+        cv::Point top = box.boxPoint[1] - box.boxPoint[0];
+        double width = cv::norm(top);
+        double height = cv::norm(box.boxPoint[3] - box.boxPoint[0]);
+        if (width < height * 2) {
+            continue;
+        }
+        float angle = float(atan2(top.y, top.x) * 180 / CV_PI);
+        if (fabs(angle) <= maxSkew) {
+            angles.emplace_back(angle, width);
+            totalLength += width;
+        }
+    }
+    float skew = 0;
+    if (int(angles.size()) >= minLines) {
+        std::sort(angles.begin(), angles.end());
+        double length = 0;
+        for (const auto &angle : angles) {
+            length += angle.second;
+            if (length * 2 >= totalLength) {
+                skew = angle.first;
+                break;
+            }
+        }
+    }
+    if (fabs(skew) < minSkew) {
+        skew = 0;
+    }
+    Logger("skewAngle(%f), lines(%d), time(%fms)\n", skew, (int) angles.size(), getCurrentTime() - startTime);
+    return skew;
+}
+
+//split a large image into overlapping tiles and detect each at full resolution, so small text is not lost by
+//scaling the whole image down to maxSideLen. boxes are moved to image coordinates, duplicates in the overlaps are
+//dropped in favour of boxes not cut by a tile edge, and lines cut on both sides of a seam are recognized again as one
+OcrResult OcrLite::detectTiled(const cv::Mat &mat, int tileSize, int tileOverlap, int padding,
+                               float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle) {
+    double startTime = getCurrentTime();
+    std::vector<TileRect> tiles = getTiles(mat.cols, mat.rows, tileSize, tileOverlap);
+    Logger("=====Start detectTiled=====\n");
+    Logger("tiles(%d),tileSize(%d),tileOverlap(%d)\n", (int) tiles.size(), tileSize, tileOverlap);
+
+    std::vector<TextBlock> candidates;
+    std::vector<TileBox> boxes;
+    double dbNetTime = 0;
+    for (int t = 0; t < tiles.size(); ++t) {
+        cv::Rect tileRect(tiles[t].x, tiles[t].y, tiles[t].width, tiles[t].height);
+        OcrResult tileResult = detect(mat(tileRect), padding, 0, boxScoreThresh, boxThresh, unClipRatio,
+                                      doAngle, mostAngle);
+        dbNetTime += tileResult.dbNetTime;
+        for (auto &block : tileResult.textBlocks) {
+            cv::Rect rect = cv::boundingRect(block.boxPoint);
+            TileRect box{rect.x, rect.y, rect.width, rect.height};
+            bool cut = isCutByTile(tiles[t], box, mat.cols, mat.rows);
+            cv::Point offset = tileRect.tl();
+            for (auto &point : block.boxPoint) {
+                point += offset;
+            }
+            for (auto &charBox : block.charBoxes) {
+                for (auto &point : charBox) {
+                    point += offset;
+                }
+            }
+            box.x += offset.x;
+            box.y += offset.y;
+            candidates.push_back(block);
+            boxes.push_back(TileBox{box, t, cut, block.boxScore});
+        }
+    }
+
+    TileMerge merge = mergeTileBoxes(boxes, mat.cols, mat.rows);
+    std::vector<TextBlock> textBlocks;
+    for (int index : merge.kept) {
+        textBlocks.push_back(candidates[index]);
+    }
+    if (!merge.lines.empty()) {
+        std::vector<cv::Mat> lines;
+        for (auto &line : merge.lines) {
+            lines.push_back(mat(cv::Rect(line.x, line.y, line.width, line.height)));
+        }
+        Logger("---------- step: recognize lines across tile seams(%d) ----------\n", (int) lines.size());
+        OcrResult linesResult = recognizeLines(lines, doAngle, mostAngle);
+        for (int i = 0; i < linesResult.textBlocks.size(); ++i) {
+            TextBlock &block = linesResult.textBlocks[i];
+            cv::Point offset(merge.lines[i].x, merge.lines[i].y);
+            for (auto &point : block.boxPoint) {
+                point += offset;
+            }
+            for (auto &charBox : block.charBoxes) {
+                for (auto &point : charBox) {
+                    point += offset;
+                }
+            }
+            block.boxScore = merge.lineScores[i];
+            textBlocks.push_back(block);
+        }
+    }
+
+    //reading order: top to bottom, then left to right
+    std::stable_sort(textBlocks.begin(), textBlocks.end(), [](const TextBlock &a, const TextBlock &b) {
+        cv::Rect ra = cv::boundingRect(a.boxPoint), rb = cv::boundingRect(b.boxPoint);
+        if (ra.y != rb.y) {
+            return ra.y < rb.y;
+        }
+        return ra.x < rb.x;
+    });
+
+    cv::Mat rgbBoxImg, boxImg;
+    cvtColor(mat, rgbBoxImg, cv::COLOR_BGR2RGB);
+    int thickness = getThickness(rgbBoxImg);
+    std::string strRes;
+    for (auto &block : textBlocks) {
+        drawTextBox(rgbBoxImg, block.boxPoint, thickness);
+        strRes.append(block.text);
+        strRes.append("\n");
+    }
+    cvtColor(rgbBoxImg, boxImg, cv::COLOR_RGB2BGR);
+
+    double fullTime = getCurrentTime() - startTime;
+    Logger("=====End detectTiled=====\n");
+    Logger("tileBoxes(%d),textBlocks(%d),FullDetectTime(%fms)\n", (int) candidates.size(), (int) textBlocks.size(),
+           fullTime);
+    return OcrResult{dbNetTime, textBlocks, boxImg, fullTime, strRes};
+}
+
+OcrResult OcrLite::recognizeLines(const std::vector<cv::Mat> &mats, bool doAngle, bool mostAngle) {
+    double startTime = getCurrentTime();
+    std::vector<cv::Mat> partImages;
+    std::vector<cv::Mat> verticalImages(mats.size());
+    for (int i = 0; i < mats.size(); ++i) {
+        cv::Mat rgb;
+        cvtColor(mats[i], rgb, cv::COLOR_BGR2RGB);// convert to RGB
+        if (isVerticalImage(rgb)) {
+            verticalImages[i] = rgb;
+            rgb = rotateVerticalCrop(rgb);
+        }
+        partImages.emplace_back(rgb);
+    }
+
+    Logger("---------- step: angleNet getAngles (lines) ----------\n");
+    std::vector<Angle> angles = angleNet.getAngles(partImages, NULL, NULL, doAngle, mostAngle);
+    for (int i = 0; i < partImages.size(); ++i) {
+        if (angles[i].index == 0) {
+            partImages.at(i) = matRotateClockWise180(partImages[i]);
+        }
+    }
+
+    Logger("---------- step: crnnNet getTextLine (lines) ----------\n");
+    std::vector<TextLine> textLines = crnnNet.getTextLines(partImages, NULL, NULL);
+    std::vector<bool> stacked(textLines.size(), false);
+    recognizeStacked(verticalImages, textLines, stacked);
+
+    std::vector<TextBlock> textBlocks;
+    std::string strRes;
+    for (int i = 0; i < textLines.size(); ++i) {
+        //the whole input image is the text box
+        int w = mats[i].cols, h = mats[i].rows;
+        std::vector<cv::Point> boxPoint{cv::Point(0, 0), cv::Point(w, 0), cv::Point(w, h), cv::Point(0, h)};
+        TextBlock textBlock{boxPoint, 1.0f, angles[i].index, angles[i].score,
+                            angles[i].time, textLines[i].text, textLines[i].charScores, textLines[i].time,
+                            angles[i].time + textLines[i].time};
+        textBlock.charCandidates = textLines[i].charCandidates;
+        textBlock.vertical = !verticalImages[i].empty();
+        for (int c = 0; c < textLines[i].charStarts.size(); ++c) {
+            textBlock.charBoxes.emplace_back(getCharBox(boxPoint, textLines[i].charStarts[c], textLines[i].charEnds[c],
+                                                        textBlock.vertical, angles[i].index == 0 && !stacked[i]));
+        }
+        textBlocks.emplace_back(textBlock);
+        strRes.append(textLines[i].text);
+        strRes.append("\n");
+    }
+
+    double fullTime = getCurrentTime() - startTime;
+    Logger("FullRecognizeLinesTime(%fms)\n", fullTime);
+    return OcrResult{0, textBlocks, cv::Mat(), fullTime, strRes};
+}
+
+static float meanCharScore(const TextLine &line) {
+    if (line.charScores.empty()) {
+        return 0.f;
+    }
+    return std::accumulate(line.charScores.begin(), line.charScores.end(), 0.f) / line.charScores.size();
+}
+
+//the rotated reading of a vertical box is kept without a stacked retry when crnn is at least this confident
+static const float kStackedRetryScore = 0.9f;
+//stacked upright chars are about as tall as the column is wide, a shorter column holds a single char at most
+static const float kStackedMinAspect = 2.0f;
+
+//vertical text is either written sideways (rotated crop reads it) or stacked upright chars (vertical chinese),
+//when the rotated reading is not confident and the column can hold several chars, recognize the stacked layout
+//too and keep the one crnn is more confident about
+void OcrLite::recognizeStacked(const std::vector<cv::Mat> &verticalImages, std::vector<TextLine> &textLines,
+                               std::vector<bool> &stacked) {
+    std::vector<int> indexes;
+    std::vector<cv::Mat> stackedImages;
+    for (int i = 0; i < verticalImages.size(); ++i) {
+        const cv::Mat &img = verticalImages[i];
+        if (img.empty() || float(img.rows) < float(img.cols) * kStackedMinAspect ||
+            meanCharScore(textLines[i]) >= kStackedRetryScore) {
+            continue;
+        }
+        indexes.emplace_back(i);
+        stackedImages.emplace_back(stackVerticalChars(img));
+    }
+    if (stackedImages.empty()) {
+        return;
+    }
+    Logger("---------- step: crnnNet getTextLine (stacked vertical) ----------\n");
+    std::vector<TextLine> stackedLines = crnnNet.getTextLines(stackedImages, NULL, NULL);
+    for (int j = 0; j < indexes.size(); ++j) {
+        TextLine &textLine = textLines[indexes[j]];
+        Logger("vertical[%d] rotated(%s, %f) stacked(%s, %f)\n", indexes[j], textLine.text.c_str(),
+               meanCharScore(textLine), stackedLines[j].text.c_str(), meanCharScore(stackedLines[j]));
+        double time = textLine.time + stackedLines[j].time;
+        if (meanCharScore(stackedLines[j]) > meanCharScore(textLine)) {
+            textLine = stackedLines[j];
+            stacked[indexes[j]] = true;
+        }
+        textLine.time = time;
+    }
+}
+
 std::vector<cv::Mat> OcrLite::getPartImages(cv::Mat &src, std::vector<TextBox> &textBoxes,
                                             const char *path, const char *imgName) {
     std::vector<cv::Mat> partImages;
@@ -179,6 +540,14 @@ OcrResult OcrLite::detect(const char *path, const char *imgName,
 
     Logger("---------- step: crnnNet getTextLine ----------\n");
     std::vector<TextLine> textLines = crnnNet.getTextLines(partImages, path, imgName);
+    std::vector<cv::Mat> verticalImages(textBoxes.size());
+    for (int i = 0; i < textBoxes.size(); ++i) {
+        if (isVerticalCrop(textBoxes[i].boxPoint)) {
+            verticalImages[i] = getPerspectiveCropImage(src, textBoxes[i].boxPoint);
+        }
+    }
+    std::vector<bool> stacked(textLines.size(), false);
+    recognizeStacked(verticalImages, textLines, stacked);
     //Log TextLines
     for (int i = 0; i < textLines.size(); ++i) {
         Logger("textLine[%d](%s)\n", i, textLines[i].text.c_str());
@@ -205,6 +574,18 @@ OcrResult OcrLite::detect(const char *path, const char *imgName,
         TextBlock textBlock{boxPoint, textBoxes[i].score, angles[i].index, angles[i].score,
                             angles[i].time, textLines[i].text, textLines[i].charScores, textLines[i].time,
                             angles[i].time + textLines[i].time};
+        textBlock.charCandidates = textLines[i].charCandidates;
+        textBlock.vertical = !verticalImages[i].empty();
+        for (int c = 0; c < textLines[i].charStarts.size(); ++c) {
+            std::vector<cv::Point> charBox = getCharBox(textBoxes[i].boxPoint, textLines[i].charStarts[c],
+                                                        textLines[i].charEnds[c], textBlock.vertical,
+                                                        angles[i].index == 0 && !stacked[i]);
+            for (auto &point : charBox) {
+                point.x -= padding;
+                point.y -= padding;
+            }
+            textBlock.charBoxes.emplace_back(charBox);
+        }
         textBlocks.emplace_back(textBlock);
     }
 
@@ -110,7 +110,35 @@ cv::Mat matRotateClockWise90(cv::Mat src) {
     return src;
 }
 
-cv::Mat getRotateCropImage(const cv::Mat &src, std::vector<cv::Point> box) {
+//rotate clockwise by 0, 90, 180 or 270 degrees, src is left untouched
+cv::Mat rotateClockWise(const cv::Mat &src, int degrees) {
+    switch ((degrees % 360 + 360) % 360) {
+        case 90:
+            return matRotateClockWise90(src.clone());
+        case 180:
+            return matRotateClockWise180(src.clone());
+        case 270:
+            return matRotateClockWise90(matRotateClockWise180(src.clone()));
+        default:
+            return src.clone();
+    }
+}
+
+//rotate src counter-clockwise by skewAngle degrees so tilted lines become level, the canvas grows to keep the
+//corners and is filled with white, matrix is the 2x3 affine transform from src to the result
+cv::Mat deskewImage(const cv::Mat &src, float skewAngle, cv::Mat &matrix) {
+    cv::Point2f center(src.cols / 2.0f, src.rows / 2.0f);
+    matrix = cv::getRotationMatrix2D(center, skewAngle, 1.0);
+    cv::Rect2f bounds = cv::RotatedRect(center, cv::Size2f(src.cols, src.rows), -skewAngle).boundingRect2f();
+    matrix.at<double>(0, 2) += bounds.width / 2.0 - center.x;
+    matrix.at<double>(1, 2) += bounds.height / 2.0 - center.y;
+    cv::Mat dst;
+    cv::warpAffine(src, dst, matrix, cv::Size(int(ceil(bounds.width)), int(ceil(bounds.height))), cv::INTER_LINEAR,
+                   cv::BORDER_CONSTANT, cv::Scalar(255, 255, 255));
+    return dst;
+}
+
+cv::Mat getPerspectiveCropImage(const cv::Mat &src, std::vector<cv::Point> box) {
     cv::Mat image;
     src.copyTo(image);
     std::vector<cv::Point> points = box;
@@ -153,17 +181,128 @@ cv::Mat getRotateCropImage(const cv::Mat &src, std::vector<cv::Point> box) {
     cv::warpPerspective(imgCrop, partImg, M,
                         cv::Size(imgCropWidth, imgCropHeight),
                         cv::BORDER_REPLICATE);
+    return partImg;
+}
+
+//rotate a vertical crop 90 degrees counterclockwise, so text written sideways reads horizontally
+cv::Mat rotateVerticalCrop(const cv::Mat &src) {
+    cv::Mat srcCopy = cv::Mat(src.rows, src.cols, src.depth());
+    cv::transpose(src, srcCopy);
+    cv::flip(srcCopy, srcCopy, 0);
+    return srcCopy;
+}
 
-    if (float(partImg.rows) >= float(partImg.cols) * 1.5) {
-        cv::Mat srcCopy = cv::Mat(partImg.rows, partImg.cols, partImg.depth());
-        cv::transpose(partImg, srcCopy);
-        cv::flip(srcCopy, srcCopy, 0);
-        return srcCopy;
+cv::Mat getRotateCropImage(const cv::Mat &src, std::vector<cv::Point> box) {
+    cv::Mat partImg = getPerspectiveCropImage(src, box);
+    if (isVerticalImage(partImg)) {
+        return rotateVerticalCrop(partImg);
     } else {
         return partImg;
     }
 }
 
+bool isVerticalImage(const cv::Mat &img) {
+    return float(img.rows) >= float(img.cols) * 1.5;
+}
+
+//cut a column of upright stacked chars (vertical chinese) into pieces and lay them out from left to right,
+//the chars stay upright so crnn reads them as a horizontal line
+cv::Mat stackVerticalChars(const cv::Mat &src) {
+    //chars are about as tall as the column is wide, cut at the blankest row near every multiple of the width
+    int charSize = (std::max)(1, src.cols);
+    int count = (std::max)(1, int(std::round(float(src.rows) / float(charSize))));
+    cv::Mat gray;
+    if (src.channels() == 3) {
+        cv::cvtColor(src, gray, cv::COLOR_BGR2GRAY);
+    } else {
+        gray = src;
+    }
+    std::vector<double> rowStdDev(src.rows);
+    for (int y = 0; y < src.rows; y++) {
+        cv::Scalar mean, stdDev;
+        cv::meanStdDev(gray.row(y), mean, stdDev);
+        rowStdDev[y] = stdDev[0];
+    }
+    std::vector<int> cuts{0};
+    for (int i = 1; i < count; i++) {
+        int center = i * src.rows / count;
+        int from = (std::max)(cuts.back() + 1, center - charSize / 3);
+        int to = (std::min)(src.rows - 1, center + charSize / 3);
+        int cut = center;
+        for (int y = from; y <= to; y++) {
+            if (rowStdDev[y] < rowStdDev[cut]) {
+                cut = y;
+            }
+        }
+        cuts.emplace_back(cut);
+    }
+    cuts.emplace_back(src.rows);
+
+    int pieceHeight = 0;
+    for (int i = 0; i + 1 < int(cuts.size()); i++) {
+        pieceHeight = (std::max)(pieceHeight, cuts[i + 1] - cuts[i]);
+    }
+    std::vector<cv::Mat> pieces;
+    for (int i = 0; i + 1 < int(cuts.size()); i++) {
+        int height = cuts[i + 1] - cuts[i];
+        if (height <= 0) {
+            continue;
+        }
+        cv::Mat piece;
+        //pad shorter pieces to the same height, keeping the chars undistorted
+        int top = (pieceHeight - height) / 2;
+        cv::copyMakeBorder(src(cv::Rect(0, cuts[i], src.cols, height)), piece,
+                           top, pieceHeight - height - top, 0, 0, cv::BORDER_REPLICATE);
+        pieces.emplace_back(piece);
+    }
+    cv::Mat stacked;
+    cv::hconcat(pieces, stacked);
+    return stacked;
+}
+
+//same rule as isVerticalImage on the crop of box: tall crops are rotated 90 degrees counterclockwise
+bool isVerticalCrop(const std::vector<cv::Point> &box) {
+    int imgCropWidth = int(sqrt(pow(box[0].x - box[1].x, 2) +
+                                pow(box[0].y - box[1].y, 2)));
+    int imgCropHeight = int(sqrt(pow(box[0].x - box[3].x, 2) +
+                                 pow(box[0].y - box[3].y, 2)));
+    return float(imgCropHeight) >= float(imgCropWidth) * 1.5;
+}
+
+//map the horizontal range [start, end] (fraction of the text line width) back into the coordinates of box,
+//reversing getRotateCropImage and the 180 degrees rotation of angleNet
+std::vector<cv::Point> getCharBox(const std::vector<cv::Point> &box, float start, float end,
+                                  bool isVertical, bool isRotated180) {
+    const float corners[4][2] = {{start, 0.f}, {end, 0.f}, {end, 1.f}, {start, 1.f}};
+    std::vector<cv::Point> charBox(4);
+    //the corner nearest to the top-left of box goes first, the same order as the text boxes
+    int first = 0;
+    float firstDistance = 2.f;
+    for (int i = 0; i < 4; i++) {
+        float u = corners[i][0];
+        float v = corners[i][1];
+        if (isRotated180) {
+            u = 1.f - u;
+            v = 1.f - v;
+        }
+        float x = u, y = v;
+        if (isVertical) {
+            x = 1.f - v;
+            y = u;
+        }
+        float px = box[0].x * (1 - x) * (1 - y) + box[1].x * x * (1 - y) + box[2].x * x * y + box[3].x * (1 - x) * y;
+        float py = box[0].y * (1 - x) * (1 - y) + box[1].y * x * (1 - y) + box[2].y * x * y + box[3].y * (1 - x) * y;
+        charBox[i] = cv::Point(int(std::round(px)), int(std::round(py)));
+        if (x + y < firstDistance) {
+            first = i;
+            firstDistance = x + y;
+        }
+    }
+    //rotating and flipping keep the corners clockwise, only the starting corner changes
+    std::rotate(charBox.begin(), charBox.begin() + first, charBox.end());
+    return charBox;
+}
+
 cv::Mat adjustTargetImg(cv::Mat &src, int dstWidth, int dstHeight) {
     cv::Mat srcResize;
     float scale = (float) dstHeight / (float) src.rows;
@@ -0,0 +1,131 @@
+#include "Tiling.h"
+#include <algorithm>
+#include <functional>
+#include <numeric>
+
+TileRect TileRect::operator&(const TileRect &other) const {
+    int left = (std::max)(x, other.x), top = (std::max)(y, other.y);
+    int r = (std::min)(right(), other.right()), b = (std::min)(bottom(), other.bottom());
+    if (r <= left || b <= top) {
+        return TileRect{0, 0, 0, 0};
+    }
+    return TileRect{left, top, r - left, b - top};
+}
+
+TileRect TileRect::operator|(const TileRect &other) const {
+    int left = (std::min)(x, other.x), top = (std::min)(y, other.y);
+    int r = (std::max)(right(), other.right()), b = (std::max)(bottom(), other.bottom());
+    return TileRect{left, top, r - left, b - top};
+}
+
+//start offsets of tiles along one side, tiles overlap by at least tileOverlap and are spread evenly,
+//a side no longer than tileSize+tileOverlap is a single tile
+std::vector<int> getTileStarts(int length, int tileSize, int tileOverlap) {
+    if (length <= tileSize + tileOverlap) {
+        return std::vector<int>{0};
+    }
+    int step = tileSize - tileOverlap;
+    int count = (length - tileOverlap + step - 1) / step;
+    std::vector<int> starts(count);
+    for (int i = 0; i < count; ++i) {
+        starts[i] = (int) ((long long) (length - tileSize) * i / (count - 1));
+    }
+    return starts;
+}
+
+std::vector<TileRect> getTiles(int width, int height, int tileSize, int tileOverlap) {
+    std::vector<int> xs = getTileStarts(width, tileSize, tileOverlap);
+    std::vector<int> ys = getTileStarts(height, tileSize, tileOverlap);
+    std::vector<TileRect> tiles;
+    for (int ty = 0; ty < int(ys.size()); ++ty) {
+        for (int tx = 0; tx < int(xs.size()); ++tx) {
+            //a single tile covers the whole side, which may be up to tileSize+tileOverlap long
+            tiles.push_back(TileRect{xs[tx], ys[ty], (std::min)(width - xs[tx], xs.size() == 1 ? width : tileSize),
+                                     (std::min)(height - ys[ty], ys.size() == 1 ? height : tileSize)});
+        }
+    }
+    return tiles;
+}
+
+bool isCutByTile(const TileRect &tile, const TileRect &box, int width, int height) {
+    const int edge = 4;//a box this close to an inner tile edge was cut by it
+    return (tile.x > 0 && box.x <= edge) ||
+           (tile.y > 0 && box.y <= edge) ||
+           (tile.right() < width && box.right() >= tile.width - edge) ||
+           (tile.bottom() < height && box.bottom() >= tile.height - edge);
+}
+
+TileMerge mergeTileBoxes(const std::vector<TileBox> &boxes, int width, int height) {
+    //whole boxes first, then larger ones. a box mostly inside an accepted one is a duplicate from the overlap,
+    //cut boxes overlapping each other are pieces of one line and are always kept to be merged below, even when
+    //one piece lies mostly inside the other, otherwise the part of the line beyond the larger piece is lost
+    std::vector<int> order(boxes.size());
+    std::iota(order.begin(), order.end(), 0);
+    std::stable_sort(order.begin(), order.end(), [&boxes](int a, int b) {
+        if (boxes[a].cut != boxes[b].cut) {
+            return !boxes[a].cut;
+        }
+        return boxes[a].rect.area() > boxes[b].rect.area();
+    });
+    auto isPiece = [](const TileBox &a, const TileBox &b) {
+        TileRect inter = a.rect & b.rect;
+        return a.cut && b.cut && a.tile != b.tile && inter.area() > 0 &&
+               (inter.height * 2 >= (std::min)(a.rect.height, b.rect.height) ||
+                inter.width * 2 >= (std::min)(a.rect.width, b.rect.width));
+    };
+    std::vector<int> accepted;
+    for (int index : order) {
+        const TileBox &candidate = boxes[index];
+        bool duplicate = false;
+        for (int other : accepted) {
+            int inter = (candidate.rect & boxes[other].rect).area();
+            if (!isPiece(candidate, boxes[other]) &&
+                inter > 0.5 * (std::min)(candidate.rect.area(), boxes[other].rect.area())) {
+                duplicate = true;
+                break;
+            }
+        }
+        if (!duplicate) {
+            accepted.push_back(index);
+        }
+    }
+
+    //group the pieces of each line, a group of several pieces is recognized again as their union
+    int count = int(accepted.size());
+    std::vector<int> group(count);
+    std::iota(group.begin(), group.end(), 0);
+    std::function<int(int)> find = [&](int i) { return group[i] == i ? i : group[i] = find(group[i]); };
+    for (int i = 0; i < count; ++i) {
+        for (int j = i + 1; j < count; ++j) {
+            int a = find(i), b = find(j);
+            //the smallest index stays the root, so a group is collected from its root onwards
+            if (a != b && isPiece(boxes[accepted[i]], boxes[accepted[j]])) {
+                group[(std::max)(a, b)] = (std::min)(a, b);
+            }
+        }
+    }
+    std::vector<int> groupSize(count, 0);
+    for (int i = 0; i < count; ++i) {
+        groupSize[find(i)]++;
+    }
+
+    TileMerge merge;
+    for (int i = 0; i < count; ++i) {
+        int root = find(i);
+        if (groupSize[root] == 1) {
+            merge.kept.push_back(accepted[i]);
+        } else if (root == i) {
+            TileRect rect = boxes[accepted[i]].rect;
+            float score = boxes[accepted[i]].score;
+            for (int j = i + 1; j < count; ++j) {
+                if (find(j) == root) {
+                    rect = rect | boxes[accepted[j]].rect;
+                    score = (std::max)(score, boxes[accepted[j]].score);
+                }
+            }
+            merge.lines.push_back(rect & TileRect{0, 0, width, height});
+            merge.lineScores.push_back(score);
+        }
+    }
+    return merge;
+}
@@ -5,95 +5,663 @@
   */
 #include "ocr.h"
 #include "OcrLite.h"
+#include "OcrUtils.h"
 #include "omp.h"
 #include "json.hpp"
+#include <opencv2/imgcodecs.hpp>
+#include <opencv2/imgproc.hpp>
 #include <iostream>
+#include <cstdio>
+#include <cstdlib>
+#include <cstring>
+#include <mutex>
 #include <sys/stat.h>
 
 using json = nlohmann::json;
 
 static OcrLite *g_ocrLite = nullptr;
 
+static thread_local int t_lastError = kOcrErrNone;
+
+static std::once_flag g_ompThreadsOnce;
+
 inline bool isFileExists(const char *name) {
     struct stat buffer{};
-    return (stat(name, &buffer) == 0);
+    return name != nullptr && (stat(name, &buffer) == 0);
 }
 
-int ocr_init(int numThread, const char *dbNetPath, const char *anglePath, const char *crnnPath, const char *keyPath) {
-    omp_set_num_threads(numThread); // 并行计算
-    if (g_ocrLite == nullptr) {
-        g_ocrLite = new OcrLite();
+/** 记录错误码，errorCode为NULL时只更新ocr_last_error */
+static void setError(int *errorCode, int code) {
+    t_lastError = code;
+    if (errorCode != nullptr) {
+        *errorCode = code;
+    }
+}
+
+const char *ocr_error_message(int code) {
+    switch (code) {
+        case kOcrErrNone:
+            return "成功";
+        case kOcrErrNotInitialized:
+            return "OCR引擎未初始化";
+        case kOcrErrFileNotFound:
+            return "文件不存在";
+        case kOcrErrDecodeImage:
+            return "图片解码失败";
+        case kOcrErrBufferTooSmall:
+            return "输出缓冲区不足";
+        case kOcrErrInference:
+            return "模型推理异常";
+        case kOcrErrInvalidArgument:
+            return "参数无效";
+        case kOcrErrImageTooLarge:
+            return "图片过大";
+        default:
+            return "未知错误";
     }
-    g_ocrLite->setNumThread(numThread);
-    g_ocrLite->initLogger(
+}
+
+int ocr_last_error() {
+    return t_lastError;
+}
+
+int ocr_schema_version() {
+    return kOcrResultSchemaVersion;
+}
+
+int ocr_params_version() {
+    return kOcrParamsVersion;
+}
+
+OcrHandle ocr_create(int numThread, const char *dbNetPath, const char *anglePath, const char *crnnPath,
+                     const char *keyPath, int *errorCode) {
+    if (!isFileExists(dbNetPath) || !isFileExists(anglePath) || !isFileExists(crnnPath) || !isFileExists(keyPath)) {
+        setError(errorCode, kOcrErrFileNotFound);
+        return nullptr;
+    }
+    // OpenMP的线程数是进程全局的，多个实例时只设置一次，每个实例的线程数通过onnxruntime的会话参数生效
+    std::call_once(g_ompThreadsOnce, [numThread]() { omp_set_num_threads(numThread); });
+    auto *ocrLite = new OcrLite();
+    ocrLite->setNumThread(numThread);
+    ocrLite->initLogger(
             false,//isOutputConsole
             false,//isOutputPartImg
             false);//isOutputResultImg
-    g_ocrLite->Logger(
-            "ocr_init numThread=%d, dbNetPath=%s,anglePath=%s,crnnPath=%s,keyPath=%s \n",
+    ocrLite->Logger(
+            "ocr_create numThread=%d, dbNetPath=%s,anglePath=%s,crnnPath=%s,keyPath=%s \n",
             numThread, dbNetPath, anglePath, crnnPath, keyPath);
-    if (!isFileExists(dbNetPath) || !isFileExists(anglePath) || !isFileExists(crnnPath) || !isFileExists(keyPath)) {
-        g_ocrLite->Logger("invalid file path.\n");
+    try {
+        ocrLite->initModels(dbNetPath, anglePath, crnnPath, keyPath);
+    } catch (const std::exception &e) {
+        ocrLite->Logger("initModels exception: %s\n", e.what());
+        delete ocrLite;
+        setError(errorCode, kOcrErrInference);
+        return nullptr;
+    }
+    setError(errorCode, kOcrErrNone);
+    return ocrLite;
+}
+
+void ocr_destroy(OcrHandle handle) {
+    delete static_cast<OcrLite *>(handle);
+}
+
+int ocr_init(int numThread, const char *dbNetPath, const char *anglePath, const char *crnnPath, const char *keyPath) {
+    ocr_cleanup();
+    g_ocrLite = static_cast<OcrLite *>(ocr_create(numThread, dbNetPath, anglePath, crnnPath, keyPath, nullptr));
+    if (g_ocrLite == nullptr) {
         return kOcrError;
     }
-    g_ocrLite->initModels(dbNetPath, anglePath, crnnPath, keyPath);
     return kOcrSuccess;
 }
 
 void ocr_cleanup() {
     if (g_ocrLite != nullptr) {
-        delete g_ocrLite;
+        ocr_destroy(g_ocrLite);
         g_ocrLite = nullptr;
     }
 }
 
-int ocr_detect(const char *image_path, char *out_buffer, int *buffer_len, int padding, int maxSideLen,
-               float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle) {
-    if (g_ocrLite == nullptr) {
-        return kOcrError;
+OcrDetectParams ocr_default_params() {
+    OcrDetectParams params{};
+    params.padding = kDefaultPadding;
+    params.maxSideLen = kDefaultMaxSideLen;
+    params.boxScoreThresh = kDefaultBoxScoreThresh;
+    params.boxThresh = kDefaultBoxThresh;
+    params.unClipRatio = kDefaultUnClipRatio;
+    params.doAngle = kDefaultDoAngle;
+    params.mostAngle = kDefaultMostAngle;
+    params.hotwordBoost = kDefaultHotwordBoost;
+    params.tileSize = kDefaultTileSize;
+    params.tileOverlap = kDefaultTileOverlap;
+    return params;
+}
+
+static OcrDetectParams paramsOrDefault(const OcrDetectParams *params) {
+    if (params == nullptr) {
+        return ocr_default_params();
+    }
+    return *params;
+}
+
+static RecognizeOptions toRecognizeOptions(const OcrDetectParams &p) {
+    RecognizeOptions options;
+    options.topK = p.topK;
+    options.beamWidth = p.beamWidth;
+    for (int i = 0; i < p.hotwordsLen && p.hotwords != nullptr; ++i) {
+        if (p.hotwords[i] != nullptr) {
+            options.hotwords.emplace_back(p.hotwords[i]);
+        }
+    }
+    options.hotwordBoost = p.hotwordBoost;
+    if (p.charWhitelist != nullptr) {
+        options.charWhitelist = p.charWhitelist;
+    }
+    if (p.charBlacklist != nullptr) {
+        options.charBlacklist = p.charBlacklist;
+    }
+    if (p.charSets != nullptr) {
+        std::string name;
+        for (const char *c = p.charSets;; ++c) {
+            if (*c == ',' || *c == '\0') {
+                if (!name.empty()) {
+                    options.charSets.emplace_back(name);
+                }
+                name.clear();
+                if (*c == '\0') {
+                    break;
+                }
+            } else {
+                name.push_back(*c);
+            }
+        }
+    }
+    return options;
+}
+
+/** 将纠偏后图片上的坐标映射回纠偏前的图片，并在纠偏前的图片上重新绘制结果图片 */
+static void mapBackDeskew(OcrResult &result, const cv::Mat &skewMatrix, const cv::Mat &original) {
+    cv::Mat inverse;
+    cv::invertAffineTransform(skewMatrix, inverse);
+    auto mapPoint = [&inverse](cv::Point &point) {
+        double x = inverse.at<double>(0, 0) * point.x + inverse.at<double>(0, 1) * point.y + inverse.at<double>(0, 2);
+        double y = inverse.at<double>(1, 0) * point.x + inverse.at<double>(1, 1) * point.y + inverse.at<double>(1, 2);
+        point = cv::Point(cvRound(x), cvRound(y));
+    };
+    for (auto &block : result.textBlocks) {
+        for (auto &point : block.boxPoint) {
+            mapPoint(point);
+        }
+        for (auto &charBox : block.charBoxes) {
+            for (auto &point : charBox) {
+                mapPoint(point);
+            }
+        }
+    }
+    if (!result.boxImg.empty()) {
+        cv::Mat rgbBoxImg;
+        cv::cvtColor(original, rgbBoxImg, cv::COLOR_BGR2RGB);
+        int thickness = getThickness(rgbBoxImg);
+        for (const auto &block : result.textBlocks) {
+            drawTextBox(rgbBoxImg, block.boxPoint, thickness);
+        }
+        cv::cvtColor(rgbBoxImg, result.boxImg, cv::COLOR_RGB2BGR);
+    }
+}
+
+/** 识别BGR图片，返回错误码，推理过程中的异常不会抛出到C调用方 */
+static int detectMat(OcrLite *ocrLite, const cv::Mat &bgr, const OcrDetectParams &p, OcrResult &result) {
+    if (ocrLite == nullptr) {
+        return kOcrErrNotInitialized;
+    }
+    if (bgr.empty()) {
+        return kOcrErrDecodeImage;
+    }
+    if (p.tile && (p.tileSize <= 0 || p.tileOverlap < 0 || p.tileOverlap >= p.tileSize)) {
+        return kOcrErrInvalidArgument;
+    }
+    ocrLite->Logger(
+            "mat(%dx%d),padding(%d),maxSideLen(%d),boxScoreThresh(%f),boxThresh(%f),unClipRatio(%f),doAngle(%d),mostAngle(%d)\n",
+            bgr.cols, bgr.rows, p.padding, p.maxSideLen, p.boxScoreThresh, p.boxThresh, p.unClipRatio, p.doAngle,
+            p.mostAngle);
+    try {
+        ocrLite->setRecognizeOptions(toRecognizeOptions(p));
+        int pageRotation = 0;
+        if (p.autoRotate) {
+            pageRotation = ocrLite->detectPageRotation(bgr, p.boxScoreThresh, p.boxThresh, p.unClipRatio);
+        }
+        cv::Mat upright = pageRotation == 0 ? bgr : rotateClockWise(bgr, pageRotation);
+        float skewAngle = 0;
+        cv::Mat page = upright, skewMatrix;
+        if (p.deskew) {
+            skewAngle = ocrLite->detectSkewAngle(upright, p.boxScoreThresh, p.boxThresh, p.unClipRatio);
+            if (skewAngle != 0) {
+                page = deskewImage(upright, skewAngle, skewMatrix);
+            }
+        }
+        if (p.tile && (std::max)(page.cols, page.rows) > p.tileSize + p.tileOverlap) {
+            result = ocrLite->detectTiled(page, p.tileSize, p.tileOverlap, p.padding, p.boxScoreThresh,
+                                          p.boxThresh, p.unClipRatio, p.doAngle != 0, p.mostAngle != 0);
+        } else {
+            result = ocrLite->detect(page, p.padding, p.maxSideLen, p.boxScoreThresh, p.boxThresh,
+                                     p.unClipRatio, p.doAngle != 0, p.mostAngle != 0);
+        }
+        if (p.deskewMapBack && !skewMatrix.empty()) {
+            mapBackDeskew(result, skewMatrix, upright);
+        }
+        result.pageRotation = pageRotation;
+        result.skewAngle = skewAngle;
+    } catch (const std::exception &e) {
+        ocrLite->Logger("detect exception: %s\n", e.what());
+        return kOcrErrInference;
+    }
+    return kOcrErrNone;
+}
+
+/** 只检测BGR图片的文本框，返回错误码 */
+static int detectBoxesMat(OcrLite *ocrLite, const cv::Mat &bgr, const OcrDetectParams &p, BoxResult &result) {
+    if (ocrLite == nullptr) {
+        return kOcrErrNotInitialized;
+    }
+    if (bgr.empty()) {
+        return kOcrErrDecodeImage;
+    }
+    ocrLite->Logger("boxes mat(%dx%d),padding(%d),maxSideLen(%d),boxScoreThresh(%f),boxThresh(%f),unClipRatio(%f)\n",
+                    bgr.cols, bgr.rows, p.padding, p.maxSideLen, p.boxScoreThresh, p.boxThresh, p.unClipRatio);
+    try {
+        result = ocrLite->detectBoxes(bgr, p.padding, p.maxSideLen, p.boxScoreThresh, p.boxThresh, p.unClipRatio);
+    } catch (const std::exception &e) {
+        ocrLite->Logger("detectBoxes exception: %s\n", e.what());
+        return kOcrErrInference;
+    }
+    return kOcrErrNone;
+}
+
+static int detectImage(OcrLite *ocrLite, const char *image_path, const OcrDetectParams &p, OcrResult &result) {
+    if (ocrLite == nullptr) {
+        return kOcrErrNotInitialized;
+    }
+    if (image_path == nullptr) {
+        return kOcrErrInvalidArgument;
     }
     if (!isFileExists(image_path)) {
-        return kOcrError;
+        return kOcrErrFileNotFound;
+    }
+    cv::Mat bgr;
+    try {
+        bgr = cv::imread(image_path, cv::IMREAD_COLOR);
+    } catch (const std::exception &e) {
+        ocrLite->Logger("imread exception: %s\n", e.what());
+        return kOcrErrDecodeImage;
+    }
+    return detectMat(ocrLite, bgr, p, result);
+}
+
+/** 解码内存中已编码的图片，返回错误码，无法解码时bgr为空；OpenCV的异常不会抛出到C调用方 */
+static int decodeBuffer(const unsigned char *data, int dataLen, cv::Mat &bgr) {
+    if (data == nullptr || dataLen <= 0) {
+        return kOcrErrInvalidArgument;
+    }
+    try {
+        cv::Mat raw(1, dataLen, CV_8UC1, const_cast<unsigned char *>(data));
+        bgr = cv::imdecode(raw, cv::IMREAD_COLOR);
+    } catch (const std::exception &e) {
+        bgr.release();
+        return kOcrErrDecodeImage;
+    }
+    return kOcrErrNone;
+}
+
+/** 将RGB像素转换为BGR图片，返回错误码；OpenCV的异常不会抛出到C调用方 */
+static int convertPixels(const unsigned char *rgb, int width, int height, int stride, cv::Mat &bgr) {
+    if (rgb == nullptr || width <= 0 || height <= 0 || stride < width * 3) {
+        return kOcrErrInvalidArgument;
     }
-    g_ocrLite->Logger(
-            "padding(%d),maxSideLen(%d),boxScoreThresh(%f),boxThresh(%f),unClipRatio(%f),doAngle(%d),mostAngle(%d)\n",
-            padding, maxSideLen, boxScoreThresh, boxThresh, unClipRatio, doAngle, mostAngle);
-    OcrResult result = g_ocrLite->detect("", image_path, padding, maxSideLen,
-                                         boxScoreThresh, boxThresh, unClipRatio, doAngle, mostAngle);
+    try {
+        cv::Mat rgbMat(height, width, CV_8UC3, const_cast<unsigned char *>(rgb), stride);
+        cv::cvtColor(rgbMat, bgr, cv::COLOR_RGB2BGR);
+    } catch (const std::exception &e) {
+        bgr.release();
+        return kOcrErrInvalidArgument;
+    }
+    return kOcrErrNone;
+}
+
+static std::string toJson(const OcrResult &result) {
     json root;
-    root["dbNetTime"] = result.dbNetTime;
-    root["detectTime"] = result.detectTime;
+    root["schema_version"] = kOcrResultSchemaVersion;
+    root["db_net_time"] = result.dbNetTime;
+    root["detect_time"] = result.detectTime;
+    root["page_rotation"] = result.pageRotation;
+    root["skew_angle"] = result.skewAngle;
+    root["texts"] = json::array();
+    root["text_blocks"] = json::array();
     for (const auto &item : result.textBlocks) {
         json textBlock;
+        textBlock["box_point"] = json::array();
         for (const auto &boxPoint : item.boxPoint) {
             json point;
             point["x"] = boxPoint.x;
             point["y"] = boxPoint.y;
-            textBlock["boxPoint"].push_back(point);
+            textBlock["box_point"].push_back(point);
         }
+        textBlock["char_scores"] = json::array();
         for (const auto &score : item.charScores) {
-            textBlock["charScores"].push_back(score);
+            textBlock["char_scores"].push_back(score);
         }
         textBlock["text"] = item.text;
-        textBlock["boxScore"] = item.boxScore;
-        textBlock["angleIndex"] = item.angleIndex;
-        textBlock["angleScore"] = item.angleScore;
-        textBlock["angleTime"] = item.angleTime;
-        textBlock["crnnTime"] = item.crnnTime;
-        textBlock["blockTime"] = item.blockTime;
-        root["textBlocks"].push_back(textBlock);
+        textBlock["box_score"] = item.boxScore;
+        textBlock["angle_index"] = item.angleIndex;
+        textBlock["angle_score"] = item.angleScore;
+        textBlock["angle_time"] = item.angleTime;
+        textBlock["crnn_time"] = item.crnnTime;
+        textBlock["block_time"] = item.blockTime;
+        textBlock["vertical"] = item.vertical;
+        root["text_blocks"].push_back(textBlock);
         root["texts"].push_back(item.text);
     }
-    std::string tempJsonStr = root.dump();
+    return root.dump();
+}
+
+/** 在结果图片上标注每个文本框的序号和置信度 */
+static void labelTextBlocks(cv::Mat &img, const std::vector<TextBlock> &textBlocks) {
+    for (int i = 0; i < static_cast<int>(textBlocks.size()); ++i) {
+        const TextBlock &block = textBlocks[i];
+        if (block.boxPoint.empty()) {
+            continue;
+        }
+        char label[32];
+        snprintf(label, sizeof(label), "%d:%.2f", i, block.boxScore);
+        cv::Point origin(block.boxPoint[0].x, (std::max)(block.boxPoint[0].y - 4, 12));
+        cv::putText(img, label, origin, cv::FONT_HERSHEY_SIMPLEX, 0.5, cv::Scalar(0, 0, 255), 1);
+    }
+}
+
+/** 按params编码结果图片，未要求或没有结果图片时返回false */
+static bool encodeResultImage(const OcrResult &result, const OcrDetectParams &p, std::vector<unsigned char> &buf) {
+    if (p.resultImage == kOcrImageNone || result.boxImg.empty()) {
+        return false;
+    }
+    try {
+        cv::Mat img = result.boxImg;
+        if (p.labelBoxes) {
+            img = result.boxImg.clone();
+            labelTextBlocks(img, result.textBlocks);
+        }
+        return cv::imencode(p.resultImage == kOcrImageJpeg ? ".jpg" : ".png", img, buf);
+    } catch (const std::exception &e) {
+        // 结果图片编码失败时只是不返回图片，识别结果仍然有效
+        buf.clear();
+        return false;
+    }
+}
+
+static char *copyString(const std::string &str) {
+    auto *out = static_cast<char *>(malloc(str.length() + 1));
+    ::memcpy(out, str.c_str(), str.length() + 1);
+    return out;
+}
+
+static OcrDetectResult *toDetectResult(const OcrResult &result, const OcrDetectParams &p) {
+    auto *out = static_cast<OcrDetectResult *>(calloc(1, sizeof(OcrDetectResult)));
+    out->schemaVersion = kOcrResultSchemaVersion;
+    out->dbNetTime = result.dbNetTime;
+    out->detectTime = result.detectTime;
+    out->pageRotation = result.pageRotation;
+    out->skewAngle = result.skewAngle;
+    std::vector<unsigned char> image;
+    if (encodeResultImage(result, p, image)) {
+        out->imageLen = static_cast<int>(image.size());
+        out->image = static_cast<unsigned char *>(malloc(image.size()));
+        ::memcpy(out->image, image.data(), image.size());
+    }
+    out->textBlocksLen = static_cast<int>(result.textBlocks.size());
+    if (out->textBlocksLen == 0) {
+        return out;
+    }
+    out->textBlocks = static_cast<OcrTextBlock *>(calloc(out->textBlocksLen, sizeof(OcrTextBlock)));
+    for (int i = 0; i < out->textBlocksLen; ++i) {
+        const TextBlock &item = result.textBlocks[i];
+        OcrTextBlock &block = out->textBlocks[i];
+        for (int j = 0; j < 4 && j < static_cast<int>(item.boxPoint.size()); ++j) {
+            block.boxPoint[j].x = item.boxPoint[j].x;
+            block.boxPoint[j].y = item.boxPoint[j].y;
+        }
+        block.boxScore = item.boxScore;
+        block.angleIndex = item.angleIndex;
+        block.angleScore = item.angleScore;
+        block.angleTime = item.angleTime;
+        block.text = copyString(item.text);
+        block.charScoresLen = static_cast<int>(item.charScores.size());
+        if (block.charScoresLen > 0) {
+            block.charScores = static_cast<float *>(malloc(block.charScoresLen * sizeof(float)));
+            ::memcpy(block.charScores, item.charScores.data(), block.charScoresLen * sizeof(float));
+        }
+        block.crnnTime = item.crnnTime;
+        block.blockTime = item.blockTime;
+        block.vertical = item.vertical;
+        if (p.charBoxes && !item.charBoxes.empty()) {
+            block.charBoxesLen = static_cast<int>(item.charBoxes.size());
+            block.charBoxes = static_cast<OcrCharBox *>(calloc(block.charBoxesLen, sizeof(OcrCharBox)));
+            for (int c = 0; c < block.charBoxesLen; ++c) {
+                for (int j = 0; j < 4 && j < static_cast<int>(item.charBoxes[c].size()); ++j) {
+                    block.charBoxes[c].boxPoint[j].x = item.charBoxes[c][j].x;
+                    block.charBoxes[c].boxPoint[j].y = item.charBoxes[c][j].y;
+                }
+            }
+        }
+        if (p.topK > 0 && !item.charCandidates.empty()) {
+            block.charCandidatesLen = static_cast<int>(item.charCandidates.size());
+            block.charCandidates = static_cast<OcrCharCandidates *>(
+                    calloc(block.charCandidatesLen, sizeof(OcrCharCandidates)));
+            for (int c = 0; c < block.charCandidatesLen; ++c) {
+                const std::vector<CharCandidate> &candidates = item.charCandidates[c];
+                OcrCharCandidates &charCandidates = block.charCandidates[c];
+                charCandidates.candidatesLen = static_cast<int>(candidates.size());
+                if (charCandidates.candidatesLen == 0) {
+                    continue;
+                }
+                charCandidates.candidates = static_cast<OcrCandidate *>(
+                        calloc(charCandidates.candidatesLen, sizeof(OcrCandidate)));
+                for (int j = 0; j < charCandidates.candidatesLen; ++j) {
+                    charCandidates.candidates[j].text = copyString(candidates[j].text);
+                    charCandidates.candidates[j].score = candidates[j].score;
+                }
+            }
+        }
+    }
+    return out;
+}
+
+int ocr_detect_handle(OcrHandle handle, const char *image_path, char *out_buffer, int *buffer_len, int padding,
+                      int maxSideLen, float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle,
+                      bool mostAngle) {
+    if (out_buffer == nullptr || buffer_len == nullptr) {
+        setError(nullptr, kOcrErrInvalidArgument);
+        return kOcrError;
+    }
+    OcrDetectParams params{};
+    params.padding = padding;
+    params.maxSideLen = maxSideLen;
+    params.boxScoreThresh = boxScoreThresh;
+    params.boxThresh = boxThresh;
+    params.unClipRatio = unClipRatio;
+    params.doAngle = doAngle;
+    params.mostAngle = mostAngle;
+
+    OcrResult result;
+    int code = detectImage(static_cast<OcrLite *>(handle), image_path, params, result);
+    if (code != kOcrErrNone) {
+        setError(nullptr, code);
+        return kOcrError;
+    }
+    std::string tempJsonStr = toJson(result);
     if (static_cast<int>(tempJsonStr.length()) > *buffer_len) {
-        g_ocrLite->Logger("buff_len is too small \n");
+        *buffer_len = static_cast<int>(tempJsonStr.length());
+        setError(nullptr, kOcrErrBufferTooSmall);
         return kOcrError;
     }
     *buffer_len = static_cast<int>(tempJsonStr.length());
     ::memcpy(out_buffer, tempJsonStr.c_str(), tempJsonStr.length());
+    setError(nullptr, kOcrErrNone);
     return kOcrSuccess;
 }
 
+/** 根据错误码转换识别结果，失败返回NULL */
+static OcrDetectResult *finishDetect(int code, const OcrResult &result, const OcrDetectParams &p, int *errorCode) {
+    setError(errorCode, code);
+    if (code != kOcrErrNone) {
+        return nullptr;
+    }
+    return toDetectResult(result, p);
+}
+
+OcrDetectResult *ocr_detect_result(OcrHandle handle, const char *image_path, const OcrDetectParams *params,
+                                   int *errorCode) {
+    OcrDetectParams p = paramsOrDefault(params);
+    OcrResult result;
+    int code = detectImage(static_cast<OcrLite *>(handle), image_path, p, result);
+    return finishDetect(code, result, p, errorCode);
+}
+
+OcrDetectResult *ocr_detect_buffer(OcrHandle handle, const unsigned char *data, int dataLen,
+                                   const OcrDetectParams *params, int *errorCode) {
+    OcrDetectParams p = paramsOrDefault(params);
+    cv::Mat bgr;
+    OcrResult result;
+    int code = decodeBuffer(data, dataLen, bgr);
+    if (code == kOcrErrNone) {
+        code = detectMat(static_cast<OcrLite *>(handle), bgr, p, result);
+    }
+    return finishDetect(code, result, p, errorCode);
+}
+
+OcrDetectResult *ocr_detect_pixels(OcrHandle handle, const unsigned char *rgb, int width, int height, int stride,
+                                   const OcrDetectParams *params, int *errorCode) {
+    OcrDetectParams p = paramsOrDefault(params);
+    cv::Mat bgr;
+    OcrResult result;
+    int code = convertPixels(rgb, width, height, stride, bgr);
+    if (code == kOcrErrNone) {
+        code = detectMat(static_cast<OcrLite *>(handle), bgr, p, result);
+    }
+    return finishDetect(code, result, p, errorCode);
+}
+
+/** 根据错误码转换文本框检测结果，失败返回NULL */
+static OcrBoxResult *finishDetectBoxes(int code, const BoxResult &result, int *errorCode) {
+    setError(errorCode, code);
+    if (code != kOcrErrNone) {
+        return nullptr;
+    }
+    auto *out = static_cast<OcrBoxResult *>(calloc(1, sizeof(OcrBoxResult)));
+    out->schemaVersion = kOcrResultSchemaVersion;
+    out->dbNetTime = result.dbNetTime;
+    out->detectTime = result.detectTime;
+    out->boxesLen = static_cast<int>(result.textBoxes.size());
+    if (out->boxesLen == 0) {
+        return out;
+    }
+    out->boxes = static_cast<OcrTextBox *>(calloc(out->boxesLen, sizeof(OcrTextBox)));
+    for (int i = 0; i < out->boxesLen; ++i) {
+        const TextBox &item = result.textBoxes[i];
+        for (int j = 0; j < 4 && j < static_cast<int>(item.boxPoint.size()); ++j) {
+            out->boxes[i].boxPoint[j].x = item.boxPoint[j].x;
+            out->boxes[i].boxPoint[j].y = item.boxPoint[j].y;
+        }
+        out->boxes[i].boxScore = item.score;
+    }
+    return out;
+}
+
+OcrBoxResult *ocr_detect_boxes_buffer(OcrHandle handle, const unsigned char *data, int dataLen,
+                                      const OcrDetectParams *params, int *errorCode) {
+    cv::Mat bgr;
+    BoxResult result;
+    int code = decodeBuffer(data, dataLen, bgr);
+    if (code == kOcrErrNone) {
+        code = detectBoxesMat(static_cast<OcrLite *>(handle), bgr, paramsOrDefault(params), result);
+    }
+    return finishDetectBoxes(code, result, errorCode);
+}
+
+OcrBoxResult *ocr_detect_boxes_pixels(OcrHandle handle, const unsigned char *rgb, int width, int height, int stride,
+                                      const OcrDetectParams *params, int *errorCode) {
+    cv::Mat bgr;
+    BoxResult result;
+    int code = convertPixels(rgb, width, height, stride, bgr);
+    if (code == kOcrErrNone) {
+        code = detectBoxesMat(static_cast<OcrLite *>(handle), bgr, paramsOrDefault(params), result);
+    }
+    return finishDetectBoxes(code, result, errorCode);
+}
+
+void ocr_free_box_result(OcrBoxResult *result) {
+    if (result == nullptr) {
+        return;
+    }
+    free(result->boxes);
+    free(result);
+}
+
+OcrDetectResult *ocr_recognize_lines(OcrHandle handle, const OcrImage *images, int imagesLen,
+                                     const OcrDetectParams *params, int *errorCode) {
+    auto *ocrLite = static_cast<OcrLite *>(handle);
+    OcrDetectParams p = paramsOrDefault(params);
+    OcrResult result;
+    if (ocrLite == nullptr) {
+        return finishDetect(kOcrErrNotInitialized, result, p, errorCode);
+    }
+    if (images == nullptr || imagesLen <= 0) {
+        return finishDetect(kOcrErrInvalidArgument, result, p, errorCode);
+    }
+    std::vector<cv::Mat> lines(imagesLen);
+    for (int i = 0; i < imagesLen; ++i) {
+        int code = convertPixels(images[i].rgb, images[i].width, images[i].height, images[i].stride, lines[i]);
+        if (code != kOcrErrNone) {
+            return finishDetect(code, result, p, errorCode);
+        }
+    }
+
+    ocrLite->Logger("recognize lines(%d),doAngle(%d),mostAngle(%d)\n", imagesLen, p.doAngle, p.mostAngle);
+    try {
+        ocrLite->setRecognizeOptions(toRecognizeOptions(p));
+        result = ocrLite->recognizeLines(lines, p.doAngle != 0, p.mostAngle != 0);
+    } catch (const std::exception &e) {
+        ocrLite->Logger("recognizeLines exception: %s\n", e.what());
+        return finishDetect(kOcrErrInference, result, p, errorCode);
+    }
+    return finishDetect(kOcrErrNone, result, p, errorCode);
+}
+
+void ocr_free_result(OcrDetectResult *result) {
+    if (result == nullptr) {
+        return;
+    }
+    for (int i = 0; i < result->textBlocksLen; ++i) {
+        free(result->textBlocks[i].text);
+        free(result->textBlocks[i].charScores);
+        free(result->textBlocks[i].charBoxes);
+        for (int c = 0; c < result->textBlocks[i].charCandidatesLen; ++c) {
+            OcrCharCandidates &charCandidates = result->textBlocks[i].charCandidates[c];
+            for (int j = 0; j < charCandidates.candidatesLen; ++j) {
+                free(charCandidates.candidates[j].text);
+            }
+            free(charCandidates.candidates);
+        }
+        free(result->textBlocks[i].charCandidates);
+    }
+    free(result->textBlocks);
+    free(result->image);
+    free(result);
+}
+
+int ocr_detect(const char *image_path, char *out_buffer, int *buffer_len, int padding, int maxSideLen,
+               float boxScoreThresh, float boxThresh, float unClipRatio, bool doAngle, bool mostAngle) {
+    return ocr_detect_handle(g_ocrLite, image_path, out_buffer, buffer_len, padding, maxSideLen, boxScoreThresh,
+                             boxThresh, unClipRatio, doAngle, mostAngle);
+}
+
 int ocr_detect2(const char *image_path, char *out_buffer, int *buffer_len) {
     return ocr_detect(image_path, out_buffer, buffer_len, kDefaultPadding, kDefaultMaxSideLen, kDefaultBoxScoreThresh,
                       kDefaultBoxThresh, kDefaultUnClipRatio, kDefaultDoAngle, kDefaultMostAngle);
@@ -0,0 +1,93 @@
+#include "CtcDecoder.h"
+#include "TestUtils.h"
+
+//类别0为blank，1为A，2为B，3为C
+static const int kClasses = 4;
+
+static std::vector<int> keysOf(const CtcPath &path) {
+    return path.keys;
+}
+
+static void testGreedyPath() {
+    //A A blank A B：重复字符之间有blank时解码为两个字符
+    std::vector<float> probs = {
+            0.1f, 0.8f, 0.05f, 0.05f,
+            0.1f, 0.8f, 0.05f, 0.05f,
+            0.8f, 0.1f, 0.05f, 0.05f,
+            0.1f, 0.8f, 0.05f, 0.05f,
+            0.1f, 0.05f, 0.8f, 0.05f,
+    };
+    HotwordTrie trie;
+    CtcPath path = ctcBeamSearch(probs, 5, kClasses, kClasses, 5, trie, 0);
+    EXPECT_EQ((std::vector<int>{1, 1, 2}), keysOf(path));
+    EXPECT_EQ((std::vector<int>{0, 3, 4}), path.steps);
+    EXPECT_NEAR(0.0f, path.starts[0], 1e-6f);
+    EXPECT_NEAR(0.4f, path.ends[0], 1e-6f);
+    EXPECT_NEAR(0.8f, path.scores[2], 1e-6f);
+}
+
+static void testHotwordBoost() {
+    //第二个字符C略高于B，热词AB加分后选择B
+    std::vector<float> probs = {
+            0.1f, 0.8f, 0.05f, 0.05f,
+            0.1f, 0.05f, 0.4f, 0.45f,
+    };
+    HotwordTrie none;
+    EXPECT_EQ((std::vector<int>{1, 3}), keysOf(ctcBeamSearch(probs, 2, kClasses, kClasses, 5, none, 1.5f)));
+
+    HotwordTrie trie;
+    trie.build({{1, 2}});
+    CtcPath path = ctcBeamSearch(probs, 2, kClasses, kClasses, 5, trie, 1.5f);
+    EXPECT_EQ((std::vector<int>{1, 2}), keysOf(path));
+    EXPECT_NEAR(0.4f, path.scores[1], 1e-6f);
+
+    //只匹配了热词前缀的结果不加分
+    trie.build({{1, 2, 3}});
+    EXPECT_EQ((std::vector<int>{1, 3}), keysOf(ctcBeamSearch(probs, 2, kClasses, kClasses, 5, trie, 1.5f)));
+}
+
+static void testMergeKeepsSourceBeam() {
+    //第0步blank的概率更高，空前缀排在前缀A之前；第1步空前缀扩展出的A与已有的前缀A合并，
+    //合并后的字符位置和得分应来自第0步就已识别出A的前缀
+    std::vector<float> probs = {
+            0.6f, 0.4f, 0.0f, 0.0f,
+            0.1f, 0.9f, 0.0f, 0.0f,
+    };
+    HotwordTrie trie;
+    CtcPath path = ctcBeamSearch(probs, 2, kClasses, kClasses, 2, trie, 0);
+    EXPECT_EQ((std::vector<int>{1}), keysOf(path));
+    EXPECT_EQ((std::vector<int>{0}), path.steps);
+    EXPECT_NEAR(0.4f, path.scores[0], 1e-6f);
+    EXPECT_NEAR(0.0f, path.starts[0], 1e-6f);
+    EXPECT_NEAR(1.0f, path.ends[0], 1e-6f);
+}
+
+static void testHotwordTrie() {
+    HotwordTrie trie;
+    trie.build({{1, 2}, {1, 2, 3}, {2}});
+    int node = 0, matched = 0;
+    trie.advance(node, matched, 1);
+    trie.advance(node, matched, 2);
+    //AB是热词，但还可能匹配ABC，不回到根节点
+    EXPECT_TRUE(trie.isEnd(node));
+    EXPECT_EQ(0, matched);
+    trie.advance(node, matched, 3);
+    EXPECT_EQ(0, node);
+    EXPECT_EQ(3, matched);
+
+    //AB之后中断，保留已完整匹配的AB，B重新开始匹配
+    node = 0, matched = 0;
+    trie.advance(node, matched, 1);
+    trie.advance(node, matched, 2);
+    trie.advance(node, matched, 2);
+    EXPECT_EQ(0, node);
+    EXPECT_EQ(3, matched);
+}
+
+int main() {
+    RUN_TEST(testGreedyPath);
+    RUN_TEST(testHotwordBoost);
+    RUN_TEST(testMergeKeepsSourceBeam);
+    RUN_TEST(testHotwordTrie);
+    return testFailures;
+}
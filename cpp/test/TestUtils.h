@@ -0,0 +1,29 @@
+#ifndef __OCR_TESTUTILS_H__
+#define __OCR_TESTUTILS_H__
+
+#include <cmath>
+#include <cstdio>
+
+//不依赖测试框架的简单断言，失败时输出位置并记录失败数，main返回失败数
+static int testFailures = 0;
+
+#define EXPECT_TRUE(cond)                                                        \
+    do {                                                                         \
+        if (!(cond)) {                                                           \
+            fprintf(stderr, "%s:%d: expect %s\n", __FILE__, __LINE__, #cond);   \
+            testFailures++;                                                      \
+        }                                                                        \
+    } while (0)
+
+#define EXPECT_EQ(expected, actual) EXPECT_TRUE((expected) == (actual))
+
+#define EXPECT_NEAR(expected, actual, eps) EXPECT_TRUE(std::fabs((expected) - (actual)) <= (eps))
+
+#define RUN_TEST(test)                           \
+    do {                                         \
+        int before = testFailures;               \
+        test();                                  \
+        printf("%s %s\n", testFailures == before ? "PASS" : "FAIL", #test); \
+    } while (0)
+
+#endif //__OCR_TESTUTILS_H__
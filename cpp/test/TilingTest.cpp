@@ -0,0 +1,95 @@
+#include "Tiling.h"
+#include "TestUtils.h"
+
+static bool sameRect(const TileRect &a, const TileRect &b) {
+    return a.x == b.x && a.y == b.y && a.width == b.width && a.height == b.height;
+}
+
+static void testTileStarts() {
+    EXPECT_EQ((std::vector<int>{0}), getTileStarts(1152, 1024, 128));
+    EXPECT_EQ((std::vector<int>{0, 488, 976}), getTileStarts(2000, 1024, 128));
+
+    //第一块从0开始，最后一块到边缘结束，相邻分块至少重叠tileOverlap
+    for (int length = 1153; length < 6000; length += 97) {
+        std::vector<int> starts = getTileStarts(length, 1024, 128);
+        EXPECT_TRUE(starts.size() > 1);
+        EXPECT_EQ(0, starts.front());
+        EXPECT_EQ(length - 1024, starts.back());
+        for (size_t i = 1; i < starts.size(); i++) {
+            EXPECT_TRUE(starts[i - 1] + 1024 - starts[i] >= 128);
+        }
+    }
+}
+
+static void testTiles() {
+    //宽2000分3列，高1000不超过tileSize+tileOverlap，只有一行且覆盖整个高度
+    std::vector<TileRect> tiles = getTiles(2000, 1100, 1024, 128);
+    EXPECT_EQ(3u, tiles.size());
+    EXPECT_TRUE(sameRect(TileRect{0, 0, 1024, 1100}, tiles[0]));
+    EXPECT_TRUE(sameRect(TileRect{488, 0, 1024, 1100}, tiles[1]));
+    EXPECT_TRUE(sameRect(TileRect{976, 0, 1024, 1100}, tiles[2]));
+
+    tiles = getTiles(2000, 2000, 1024, 128);
+    EXPECT_EQ(9u, tiles.size());
+    //下标为行号*列数+列号
+    EXPECT_TRUE(sameRect(TileRect{488, 976, 1024, 1024}, tiles[1 + 2 * 3]));
+}
+
+static void testCutByTile() {
+    TileRect middle{488, 0, 1024, 1100};
+    EXPECT_TRUE(isCutByTile(middle, TileRect{2, 100, 50, 20}, 2000, 1100));
+    EXPECT_TRUE(isCutByTile(middle, TileRect{900, 100, 122, 20}, 2000, 1100));
+    EXPECT_TRUE(!isCutByTile(middle, TileRect{100, 100, 50, 20}, 2000, 1100));
+    //整图的边缘不算截断
+    TileRect first{0, 0, 1024, 1100};
+    EXPECT_TRUE(!isCutByTile(first, TileRect{0, 0, 50, 20}, 2000, 1100));
+    EXPECT_TRUE(!isCutByTile(first, TileRect{100, 1080, 50, 20}, 2000, 1100));
+}
+
+static void testMergeSeam() {
+    std::vector<TileBox> boxes{
+            //跨越第0、1块接缝的文本行，在两块中都被截断，第0块中的片段大部分位于第1块的片段中
+            TileBox{TileRect{450, 100, 574, 30}, 0, true, 0.7f},
+            TileBox{TileRect{488, 102, 600, 28}, 1, true, 0.9f},
+            //完全位于重叠区域的文本框在两块中都被完整检测到
+            TileBox{TileRect{600, 300, 100, 30}, 0, false, 0.8f},
+            TileBox{TileRect{601, 301, 98, 29}, 1, false, 0.8f},
+            //只在一块中的文本框
+            TileBox{TileRect{100, 500, 200, 30}, 0, false, 0.6f},
+    };
+    TileMerge merge = mergeTileBoxes(boxes, 2000, 1100);
+    //kept按完整优先、面积从大到小的顺序排列
+    EXPECT_EQ((std::vector<int>{4, 2}), merge.kept);
+    if (merge.lines.size() != 1) {
+        EXPECT_EQ(1u, merge.lines.size());
+        return;
+    }
+    EXPECT_TRUE(sameRect(TileRect{450, 100, 638, 30}, merge.lines[0]));
+    EXPECT_NEAR(0.9f, merge.lineScores[0], 1e-6f);
+}
+
+static void testMergeSeamChain() {
+    //A与B不相交，都与C相交，三个片段合并为一行
+    std::vector<TileBox> boxes{
+            TileBox{TileRect{0, 0, 100, 20}, 0, true, 0.5f},
+            TileBox{TileRect{200, 0, 100, 20}, 1, true, 0.6f},
+            TileBox{TileRect{90, 2, 120, 16}, 2, true, 0.7f},
+    };
+    TileMerge merge = mergeTileBoxes(boxes, 2000, 1100);
+    EXPECT_TRUE(merge.kept.empty());
+    if (merge.lines.size() != 1) {
+        EXPECT_EQ(1u, merge.lines.size());
+        return;
+    }
+    EXPECT_TRUE(sameRect(TileRect{0, 0, 300, 20}, merge.lines[0]));
+    EXPECT_NEAR(0.7f, merge.lineScores[0], 1e-6f);
+}
+
+int main() {
+    RUN_TEST(testTileStarts);
+    RUN_TEST(testTiles);
+    RUN_TEST(testCutByTile);
+    RUN_TEST(testMergeSeam);
+    RUN_TEST(testMergeSeamChain);
+    return testFailures;
+}
@@ -6,6 +6,7 @@ require (
 	github.com/gin-gonic/gin v1.9.1
 	github.com/makiuchi-d/gozxing v0.1.1
 	github.com/stretchr/testify v1.8.3
+	golang.org/x/image v0.7.0
 )
 
 require (
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"ocr/src"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,17 +21,16 @@ func main() {
 	}
 
 	// 初始化OCR
-	log.Println("正在初始化OCR引擎...")
-	if result := src.Init(); result != 0 {
-		log.Printf("OCR初始化完成，返回值: %d", result)
-	} else {
-		log.Println("OCR初始化可能失败，但服务将继续运行")
-	}
+	config := src.LoadConfig()
+	profiles := newProfiles(config)
+	go reloadOnSignal(profiles)
 
 	// 确保在程序退出时清理资源
 	defer func() {
 		log.Println("正在清理OCR资源...")
-		src.CleanUp()
+		if err := profiles.Close(); err != nil {
+			log.Printf("清理OCR资源失败: %v", err)
+		}
 		log.Println("服务已停止")
 	}()
 
@@ -50,7 +52,7 @@ func main() {
 	r.Use(corsMiddleware())
 
 	// 注册路由
-	setupRoutes(r)
+	setupRoutes(r, profiles, config)
 
 	// 获取端口
 	port := os.Getenv("PORT")
@@ -64,9 +66,90 @@ func main() {
 	}
 }
 
+// newProfiles 加载所有模型配置，每个配置使用独立的可重新加载的引擎
+func newProfiles(config src.Config) *src.ProfileSet {
+	defaultName, profileConfigs, err := src.LoadProfileConfigs(config)
+	if err != nil {
+		log.Fatalf("加载模型配置失败: %v", err)
+	}
+
+	profiles := make([]*src.Profile, 0, len(profileConfigs))
+	for _, profileConfig := range profileConfigs {
+		detect, err := profileConfig.Detect.Resolve(config.Detect)
+		if err != nil {
+			log.Fatalf("模型配置%s的检测参数无效: %v", profileConfig.Name, err)
+		}
+		engine := src.NewReloadableEngine(newEngine(config, profileConfig), profileConfig.Manifest, detect,
+			func(manifest string) (src.Engine, error) {
+				return loadEngine(config, manifest)
+			})
+		profiles = append(profiles, &src.Profile{Name: profileConfig.Name, Engine: engine, Detect: detect})
+	}
+
+	profileSet, err := src.NewProfileSet(defaultName, profiles...)
+	if err != nil {
+		log.Fatalf("加载模型配置失败: %v", err)
+	}
+	return profileSet
+}
+
+// newEngine 加载并校验模型配置中的模型后创建OCR引擎
+//
+// 失败时如果配置了OCR_REQUIRE_MODELS则拒绝启动，否则返回不可用的引擎，服务继续运行。
+func newEngine(config src.Config, profile src.ProfileConfig) src.Engine {
+	engine, err := loadEngine(config, profile.Manifest)
+	if err == nil {
+		log.Printf("模型配置%s初始化完成，引擎: %s", profile.Name, engine.Info().Name)
+		return engine
+	}
+
+	if config.RequireModels {
+		log.Fatalf("模型配置%s初始化失败，拒绝启动: %v", profile.Name, err)
+	}
+	log.Printf("模型配置%s初始化失败，服务将继续运行但该配置不可用: %v", profile.Name, err)
+	return src.NewUnavailableEngine(err)
+}
+
+// loadEngine 加载并校验manifest中的模型，创建OCR引擎
+func loadEngine(config src.Config, manifest string) (src.Engine, error) {
+	models, err := src.LoadModelManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("模型集: %s %s，清单: %s", models.Name, models.Version, manifest)
+	if err := models.Validate(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("正在初始化OCR引擎，实例数: %d，每个实例线程数: %d，等待队列: %d",
+		config.PoolSize, config.ThreadNum, config.QueueSize)
+	return src.NewOcrLiteEngine(config, models)
+}
+
+// reloadOnSignal 收到SIGHUP时重新加载所有模型配置的当前模型清单
+func reloadOnSignal(profiles *src.ProfileSet) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		log.Println("收到SIGHUP，重新加载模型")
+		for _, name := range profiles.Names() {
+			profile, _ := profiles.Get(name)
+			engine, ok := profile.Engine.(*src.ReloadableEngine)
+			if !ok {
+				continue
+			}
+			if err := engine.Reload(context.Background(), ""); err != nil {
+				log.Printf("模型配置%s重新加载失败: %v", name, err)
+			} else {
+				log.Printf("模型配置%s重新加载完成", name)
+			}
+		}
+	}
+}
+
 // ensureRequiredDirectories 确保必要的目录存在
 func ensureRequiredDirectories() error {
-	directories := []string{"./tmp", "./models"}
+	directories := []string{"./models"}
 
 	for _, dir := range directories {
 		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
@@ -79,20 +162,67 @@ func ensureRequiredDirectories() error {
 }
 
 // setupRoutes 设置API路由
-func setupRoutes(r *gin.Engine) {
+func setupRoutes(r *gin.Engine, profiles *src.ProfileSet, config src.Config) {
+	handler := src.NewProfileOcrHandler(profiles)
+	handler.MaxPages = config.MaxPages
+
 	// API组
 	api := r.Group("/api")
 	{
-		api.POST("/ocr", src.OcrJson)
-		api.POST("/ocr_file", src.OcrFile)
+		api.POST("/ocr", handler.OcrJson)
+		api.POST("/ocr_file", handler.OcrFile)
+		api.POST("/ocr_image", handler.OcrImage)
+		api.POST("/detect", handler.Detect)
+		api.POST("/recognize_lines", handler.RecognizeLines)
 	}
 
-	// 健康检查接口
-	r.GET("/health", func(c *gin.Context) {
+	// 管理接口，仅在配置了访问令牌时开放
+	if config.AdminToken != "" {
+		admin := src.NewAdminHandler(profiles, config.AdminToken)
+		adminGroup := r.Group("/admin", admin.Auth)
+		{
+			adminGroup.POST("/reload", admin.Reload)
+		}
+	}
+
+	// 服务说明
+	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
-			"status":  "ok",
-			"service": "go-ocr",
+			"message": "Go OCR Service",
 			"version": "1.0",
+			"endpoints": gin.H{
+				"ocr":             "POST /api/ocr",
+				"ocr_file":        "POST /api/ocr_file",
+				"ocr_image":       "POST /api/ocr_image",
+				"detect":          "POST /api/detect",
+				"recognize_lines": "POST /api/recognize_lines",
+				"health":          "GET /health",
+			},
+		})
+	})
+
+	// 健康检查接口
+	// 部分模型配置不可用时为degraded，全部不可用时返回503
+	r.GET("/health", func(c *gin.Context) {
+		infos := profiles.Info()
+		unavailable := 0
+		for _, info := range infos {
+			if info.Engine.Error != "" {
+				unavailable++
+			}
+		}
+		status, code := "ok", 200
+		if unavailable == len(infos) {
+			status, code = "unavailable", 503
+		} else if unavailable > 0 {
+			status = "degraded"
+		}
+		c.JSON(code, gin.H{
+			"status":   status,
+			"service":  "go-ocr",
+			"version":  "1.0",
+			"engine":   profiles.Default().Engine.Info(),
+			"profiles": infos,
 		})
 	})
 
@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"ocr/src"
 	"os"
 	"testing"
 
@@ -16,6 +18,13 @@ func init() {
 	gin.SetMode(gin.TestMode)
 }
 
+// singleProfile 使用engine创建只有默认配置的模型配置集合
+func singleProfile(t *testing.T, engine src.Engine) *src.ProfileSet {
+	profiles, err := src.NewProfileSet("default", &src.Profile{Name: "default", Engine: engine, Detect: src.DefaultDetectParams()})
+	assert.NoError(t, err)
+	return profiles
+}
+
 func TestEnsureRequiredDirectories(t *testing.T) {
 	// 创建临时目录用于测试
 	testBaseDir := "test_dirs"
@@ -32,9 +41,6 @@ func TestEnsureRequiredDirectories(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 验证目录是否存在
-	_, err = os.Stat("./tmp")
-	assert.NoError(t, err)
-
 	_, err = os.Stat("./models")
 	assert.NoError(t, err)
 }
@@ -42,7 +48,7 @@ func TestEnsureRequiredDirectories(t *testing.T) {
 func TestSetupRoutes(t *testing.T) {
 	// 创建测试路由器
 	r := gin.New()
-	setupRoutes(r)
+	setupRoutes(r, singleProfile(t, src.NewFakeEngine()), src.DefaultConfig())
 
 	// 测试根路径
 	t.Run("root endpoint", func(t *testing.T) {
@@ -74,6 +80,83 @@ func TestSetupRoutes(t *testing.T) {
 		assert.Equal(t, "ok", response["status"])
 		assert.Equal(t, "go-ocr", response["service"])
 		assert.Equal(t, "1.0", response["version"])
+		assert.Equal(t, "fake", response["engine"].(map[string]interface{})["name"])
+	})
+}
+
+func TestHealthUnavailable(t *testing.T) {
+	r := gin.New()
+	setupRoutes(r, singleProfile(t, src.NewUnavailableEngine(errors.New("crnn模型文件不存在"))), src.DefaultConfig())
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "unavailable", response["status"])
+	assert.Contains(t, response["engine"].(map[string]interface{})["error"], "crnn模型文件不存在")
+}
+
+func TestHealthDegraded(t *testing.T) {
+	profiles, err := src.NewProfileSet("general",
+		&src.Profile{Name: "general", Engine: src.NewFakeEngine(), Detect: src.DefaultDetectParams()},
+		&src.Profile{Name: "english", Engine: src.NewUnavailableEngine(errors.New("keys文件不存在")), Detect: src.DefaultDetectParams()})
+	assert.NoError(t, err)
+	r := gin.New()
+	setupRoutes(r, profiles, src.DefaultConfig())
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "degraded", response["status"])
+	infos := response["profiles"].([]interface{})
+	assert.Len(t, infos, 2)
+	assert.Equal(t, "english", infos[0].(map[string]interface{})["name"])
+	assert.Equal(t, true, infos[1].(map[string]interface{})["default"])
+}
+
+func TestNewEngineInvalidModels(t *testing.T) {
+	config := src.DefaultConfig()
+
+	engine := newEngine(config, src.ProfileConfig{Name: "default", Manifest: "non-existent/manifest.json"})
+	assert.Equal(t, "unavailable", engine.Info().Name)
+	assert.Contains(t, engine.Info().Error, "读取模型清单失败")
+}
+
+func TestAdminRoutes(t *testing.T) {
+	reloadable := src.NewReloadableEngine(src.NewFakeEngine(), "", src.DefaultDetectParams(), func(manifest string) (src.Engine, error) {
+		return src.NewFakeEngine(), nil
+	})
+
+	t.Run("disabled without token", func(t *testing.T) {
+		r := gin.New()
+		setupRoutes(r, singleProfile(t, reloadable), src.DefaultConfig())
+
+		req, _ := http.NewRequest("POST", "/admin/reload", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("enabled with token", func(t *testing.T) {
+		config := src.DefaultConfig()
+		config.AdminToken = "secret"
+		r := gin.New()
+		setupRoutes(r, singleProfile(t, reloadable), config)
+
+		req, _ := http.NewRequest("POST", "/admin/reload", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
 
@@ -0,0 +1,70 @@
+package src
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ReloadDTO 重新加载模型的请求参数
+type ReloadDTO struct {
+	// Profile 要重新加载的模型配置，为空时为默认配置
+	Profile string `json:"profile"`
+	// Manifest 新的模型清单路径，为空时重新加载当前清单
+	Manifest string `json:"manifest"`
+}
+
+// AdminHandler 管理接口处理器
+type AdminHandler struct {
+	profiles *ProfileSet
+	token    string
+}
+
+// NewAdminHandler 创建管理接口处理器，请求需要携带token
+func NewAdminHandler(profiles *ProfileSet, token string) *AdminHandler {
+	return &AdminHandler{profiles: profiles, token: token}
+}
+
+// Auth 校验请求头Authorization: Bearer <token>
+func (h *AdminHandler) Auth(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if h.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, Response{Code: 401, Msg: "未授权", Data: nil})
+		return
+	}
+	c.Next()
+}
+
+// Reload 重新加载模型
+func (h *AdminHandler) Reload(c *gin.Context) {
+	var input ReloadDTO
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindWith(&input, binding.JSON); err != nil {
+			log.Printf("参数绑定失败: %v", err)
+			SendError(c, "参数格式错误: "+err.Error())
+			return
+		}
+	}
+
+	profile, err := h.profiles.Get(input.Profile)
+	if err != nil {
+		SendErrorCode(c, ResponseCode(err), err.Error())
+		return
+	}
+	engine, ok := profile.Engine.(*ReloadableEngine)
+	if !ok {
+		SendError(c, "模型配置"+profile.Name+"不支持重新加载")
+		return
+	}
+
+	if err := engine.Reload(c.Request.Context(), input.Manifest); err != nil {
+		log.Printf("模型配置%s重新加载失败: %v", profile.Name, err)
+		SendErrorCode(c, ResponseCode(err), "模型重新加载失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, Response{Code: 200, Msg: "ok", Data: engine.Info()})
+}
@@ -0,0 +1,99 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminReload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(loader EngineLoader) *gin.Engine {
+		engine := NewReloadableEngine(NewFakeEngine(), "models/manifest.json", DefaultDetectParams(), loader)
+		profiles, err := NewProfileSet("general",
+			&Profile{Name: "general", Engine: engine, Detect: DefaultDetectParams()},
+			&Profile{Name: "static", Engine: NewFakeEngine(), Detect: DefaultDetectParams()})
+		assert.NoError(t, err)
+		admin := NewAdminHandler(profiles, "secret")
+		router := gin.New()
+		router.POST("/admin/reload", admin.Auth, admin.Reload)
+		return router
+	}
+
+	post := func(router *gin.Engine, token string, body string) (*httptest.ResponseRecorder, Response) {
+		req, _ := http.NewRequest("POST", "/admin/reload", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		router := newRouter(func(manifest string) (Engine, error) { return NewFakeEngine(), nil })
+
+		w, _ := post(router, "", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w, _ = post(router, "wrong", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("reload manifest", func(t *testing.T) {
+		var loaded string
+		router := newRouter(func(manifest string) (Engine, error) {
+			loaded = manifest
+			return NewFakeEngine(), nil
+		})
+
+		w, response := post(router, "secret", `{"manifest": "models/v2/manifest.json"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, "models/v2/manifest.json", loaded)
+		assert.Equal(t, "fake", response.Data.(map[string]interface{})["name"])
+	})
+
+	t.Run("manifest outside directory", func(t *testing.T) {
+		router := newRouter(func(manifest string) (Engine, error) {
+			t.Fatal("不应加载模型清单目录之外的文件")
+			return nil, nil
+		})
+
+		_, response := post(router, "secret", `{"manifest": "/tmp/manifest.json"}`)
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "models")
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		router := newRouter(func(manifest string) (Engine, error) { return NewFakeEngine(), nil })
+
+		_, response := post(router, "secret", `{"profile": "english"}`)
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "general, static")
+
+		_, response = post(router, "secret", `{"profile": "static"}`)
+		assert.Equal(t, 500, response.Code)
+		assert.Contains(t, response.Msg, "不支持重新加载")
+	})
+
+	t.Run("reload failure", func(t *testing.T) {
+		router := newRouter(func(manifest string) (Engine, error) {
+			return nil, errors.Join(ErrFileNotFound, errors.New("crnn模型文件不存在"))
+		})
+
+		_, response := post(router, "secret", "")
+		assert.Equal(t, 5002, response.Code)
+		assert.Contains(t, response.Msg, "继续使用原模型")
+	})
+}
@@ -1,10 +1,12 @@
 package src
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +17,35 @@ type OcrDTO struct {
 	ImageUrl    string `json:"image_url" binding:"omitempty,url"`
 	ImageBase64 string `json:"image_base_64"`
 	NeedBlock   bool   `json:"need_block"`
-	QrCode      bool   `json:"qr_code"` // 是否识别二维码
+	QrCode      bool   `json:"qr_code"`      // 是否识别二维码
+	Profile     string `json:"profile"`      // 模型配置名称，为空时使用默认配置
+	ReturnImage bool   `json:"return_image"` // 是否返回标注了文本框的结果图片
+	ImageFormat string `json:"image_format"` // 结果图片格式，png或jpeg，默认为png
+	LabelBoxes  bool   `json:"label_boxes"`  // 是否在结果图片上标注文本框序号和置信度
+	CharBoxes   bool   `json:"char_boxes"`   // 是否返回每个字符的文本框，需要同时设置need_block
+	TopK        int    `json:"top_k"`        // 每个字符返回的候选字符数量，需要同时设置need_block
+	PageRange   string `json:"page_range"`   // 多页TIFF、GIF识别的页码范围，例如1-3,5，为空时识别所有页
+	DetectOptions
+}
+
+// kMaxLineImages 文本行识别接口单次请求的最大图片数
+const kMaxLineImages = 64
+
+// RecognizeLinesDTO 文本行识别的请求参数
+type RecognizeLinesDTO struct {
+	ImagesBase64 []string `json:"images_base_64"` // 已裁剪好的文本行图片
+	Profile      string   `json:"profile"`        // 模型配置名称，为空时使用默认配置
+	DoAngle      *bool    `json:"do_angle"`       // 是否进行方向检测，默认为false
+	MostAngle    *bool    `json:"most_angle"`     // 是否按多数文本行的方向统一方向，默认为false
+	CharBoxes    bool     `json:"char_boxes"`     // 是否返回每个字符的文本框
+	TopK         int      `json:"top_k"`          // 每个字符返回的候选字符数量
+	BeamWidth    *int     `json:"beam_width"`     // CTC beam search的beam宽度，默认使用模型配置
+	Hotwords     []string `json:"hotwords"`       // 追加到模型配置的热词
+	HotwordBoost *float64 `json:"hotword_boost"`  // 热词每个字符的加分，默认使用模型配置
+	// 字符白名单、黑名单和命名字符集，默认使用模型配置
+	CharWhitelist *string  `json:"char_whitelist"`
+	CharBlacklist *string  `json:"char_blacklist"`
+	CharSets      []string `json:"char_sets"`
 }
 
 type Response struct {
@@ -43,57 +73,158 @@ func isValidBase64(s string) bool {
 	return len(s) > 0 && len(s)%4 == 0 && !strings.ContainsAny(s, " \t\n\r")
 }
 
-func OcrJson(c *gin.Context) {
+// OcrHandler OCR接口处理器，识别工作交给请求选择的模型配置的引擎完成
+type OcrHandler struct {
+	profiles *ProfileSet
+	// MaxPages 多页图片单次请求最多识别的页数
+	MaxPages int
+}
+
+// NewOcrHandler 使用指定的引擎创建接口处理器，defaults为请求未指定时使用的检测参数
+func NewOcrHandler(engine Engine, defaults DetectParams) *OcrHandler {
+	profiles, _ := NewProfileSet(kDefaultProfile, &Profile{Name: kDefaultProfile, Engine: engine, Detect: defaults})
+	return NewProfileOcrHandler(profiles)
+}
+
+// NewProfileOcrHandler 使用多套模型配置创建接口处理器
+func NewProfileOcrHandler(profiles *ProfileSet) *OcrHandler {
+	return &OcrHandler{profiles: profiles, MaxPages: kDefaultMaxPages}
+}
+
+func (h *OcrHandler) OcrJson(c *gin.Context) {
 	var input OcrDTO
-	if err := c.ShouldBindWith(&input, binding.JSON); err != nil {
-		log.Printf("参数绑定失败: %v", err)
-		SendError(c, "参数格式错误: "+err.Error())
+	if !bindOcrJson(c, &input) {
+		return
+	}
+	profile, params, imageData, ok := h.prepare(c, &input)
+	if !ok {
 		return
 	}
 
-	// 验证输入参数
-	if err := validateOcrDTO(&input); err != nil {
-		log.Printf("参数验证失败: %v", err)
-		SendError(c, err.Error())
+	// 识别
+	response, err := h.performOCR(c.Request.Context(), profile, imageData, input, params)
+	if err != nil {
+		log.Printf("OCR识别失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), "OCR识别失败: "+err.Error())
+	} else {
+		log.Printf("OCR识别成功，图片大小: %d", len(imageData))
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// OcrImage 识别图片并直接返回标注了文本框的结果图片，失败时仍返回json错误
+func (h *OcrHandler) OcrImage(c *gin.Context) {
+	var input OcrDTO
+	if !bindOcrJson(c, &input) {
+		return
+	}
+	input.ReturnImage = true
+	profile, params, imageData, ok := h.prepare(c, &input)
+	if !ok {
 		return
 	}
 
-	var imagePath string
-	var err error
+	result, err := recognize(c.Request.Context(), profile.Engine, imageData, params)
+	if err != nil {
+		log.Printf("OCR识别失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), "OCR识别失败: "+err.Error())
+		return
+	}
+	if len(result.Image) == 0 {
+		SendError(c, "OCR引擎未生成结果图片")
+		return
+	}
+	c.Data(http.StatusOK, "image/"+params.ResultImage, result.Image)
+}
 
-	if input.ImageBase64 != "" {
-		log.Println("处理base64图片")
-		imagePath, err = saveBase64Image(input.ImageBase64)
-	} else if input.ImageUrl != "" {
-		log.Printf("下载图片: %s", input.ImageUrl)
-		imagePath, err = downloadAndSaveImage(input.ImageUrl)
+// Detect 只检测文本框，返回原图坐标下的文本框及置信度，不识别文字
+func (h *OcrHandler) Detect(c *gin.Context) {
+	var input OcrDTO
+	if !bindOcrJson(c, &input) {
+		return
+	}
+	profile, params, imageData, ok := h.prepare(c, &input)
+	if !ok {
+		return
 	}
 
+	result, err := detect(c.Request.Context(), profile.Engine, imageData, params)
 	if err != nil {
-		log.Printf("图片处理失败: %v", err)
-		SendError(c, "图片处理失败: "+err.Error())
+		log.Printf("文本检测失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), "文本检测失败: "+err.Error())
 		return
 	}
+	log.Printf("文本检测成功，文本框数量: %d", len(result.Boxes))
+	c.JSON(http.StatusOK, Response{Code: 200, Msg: "ok", Data: result})
+}
 
-	// 确保文件存在
-	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		log.Printf("图片文件不存在: %s", imagePath)
-		SendError(c, "图片文件处理失败")
+// RecognizeLines 跳过文本检测，将每张图片作为一个文本行识别，返回每张图片的文字及字符置信度
+func (h *OcrHandler) RecognizeLines(c *gin.Context) {
+	var input RecognizeLinesDTO
+	if err := c.ShouldBindWith(&input, binding.JSON); err != nil {
+		log.Printf("参数绑定失败: %v", err)
+		SendError(c, "参数格式错误: "+err.Error())
+		return
+	}
+	if len(input.ImagesBase64) == 0 {
+		SendError(c, "images_base_64不能为空")
+		return
+	}
+	if len(input.ImagesBase64) > kMaxLineImages {
+		SendError(c, fmt.Sprintf("单次最多识别%d张文本行图片", kMaxLineImages))
 		return
 	}
 
-	// 识别
-	response, err := performOCR(imagePath, input)
+	profile, err := h.profiles.Get(input.Profile)
 	if err != nil {
-		log.Printf("OCR识别失败: %v", err)
-		SendError(c, "OCR识别失败: "+err.Error())
-	} else {
-		log.Printf("OCR识别成功: %s", imagePath)
-		c.JSON(http.StatusOK, response)
+		SendErrorCode(c, ResponseCode(err), err.Error())
+		return
+	}
+	params := profile.Detect
+	params.DoAngle = input.DoAngle != nil && *input.DoAngle
+	params.MostAngle = input.MostAngle != nil && *input.MostAngle
+	params.CharBoxes = input.CharBoxes
+	params.TopK = input.TopK
+	decodeOptions := DetectOptions{
+		BeamWidth:     input.BeamWidth,
+		Hotwords:      input.Hotwords,
+		HotwordBoost:  input.HotwordBoost,
+		CharWhitelist: input.CharWhitelist,
+		CharBlacklist: input.CharBlacklist,
+		CharSets:      input.CharSets,
 	}
+	params, err = decodeOptions.Resolve(params)
+	if err != nil {
+		SendErrorCode(c, ResponseCode(err), "检测参数无效: "+err.Error())
+		return
+	}
+
+	lines := make([]image.Image, 0, len(input.ImagesBase64))
+	for i, imageBase64 := range input.ImagesBase64 {
+		var img image.Image
+		data, err := decodeBase64Image(imageBase64)
+		if err == nil {
+			img, _, err = decodeOrientedImage(data)
+		}
+		if err != nil {
+			log.Printf("第%d张图片处理失败: %v", i+1, err)
+			SendErrorCode(c, ResponseCode(err), fmt.Sprintf("第%d张图片处理失败: %v", i+1, err))
+			return
+		}
+		lines = append(lines, img)
+	}
+
+	result, err := profile.Engine.RecognizeLines(c.Request.Context(), lines, params)
+	if err != nil {
+		log.Printf("文本行识别失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), "文本行识别失败: "+err.Error())
+		return
+	}
+	log.Printf("文本行识别成功，图片数量: %d", len(lines))
+	c.JSON(http.StatusOK, Response{Code: 200, Msg: "ok", Data: result})
 }
 
-func OcrFile(c *gin.Context) {
+func (h *OcrHandler) OcrFile(c *gin.Context) {
 	var input OcrDTO
 
 	// 获取上传的文件
@@ -107,7 +238,7 @@ func OcrFile(c *gin.Context) {
 	// 验证文件类型
 	if !isValidImageFile(file.Filename) {
 		log.Printf("不支持的文件类型: %s", file.Filename)
-		SendError(c, "不支持的文件类型，请上传jpg、jpeg、png格式的图片")
+		SendError(c, "不支持的文件类型，请上传"+strings.Join(SupportedImageExtensions(), "、")+"格式的图片")
 		return
 	}
 
@@ -118,84 +249,251 @@ func OcrFile(c *gin.Context) {
 	if c.DefaultPostForm("qr_code", "") == "true" {
 		input.QrCode = true
 	}
-
-	log.Printf("处理上传文件: %s", file.Filename)
-
-	// 确保tmp目录存在
-	if err := os.MkdirAll("./tmp", os.ModePerm); err != nil {
-		log.Printf("创建临时目录失败: %v", err)
-		SendError(c, "服务器内部错误")
+	input.ReturnImage = c.PostForm("return_image") == "true"
+	input.ImageFormat = c.PostForm("image_format")
+	input.LabelBoxes = c.PostForm("label_boxes") == "true"
+	input.CharBoxes = c.PostForm("char_boxes") == "true"
+	if topK := c.PostForm("top_k"); topK != "" {
+		if input.TopK, err = strconv.Atoi(topK); err != nil {
+			SendError(c, "top_k必须为整数: "+topK)
+			return
+		}
+	}
+	input.Profile = c.PostForm("profile")
+	input.PageRange = c.PostForm("page_range")
+	if err := c.ShouldBindWith(&input.DetectOptions, binding.Form); err != nil {
+		log.Printf("参数绑定失败: %v", err)
+		SendError(c, "参数格式错误: "+err.Error())
+		return
+	}
+	profile, params, err := h.resolve(&input)
+	if err != nil {
+		log.Printf("参数验证失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), err.Error())
 		return
 	}
 
-	imagePath := "./tmp/" + file.Filename
+	log.Printf("处理上传文件: %s", file.Filename)
 
-	// 保存上传的文件
-	if err := c.SaveUploadedFile(file, imagePath); err != nil {
-		log.Printf("保存上传文件失败: %v", err)
-		SendError(c, "保存文件失败: "+err.Error())
+	// 读取上传的文件
+	imageData, err := readUploadedImage(file)
+	if err != nil {
+		log.Printf("读取上传文件失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), "读取文件失败: "+err.Error())
 		return
 	}
 
 	// 识别
-	response, err := performOCR(imagePath, input)
+	response, err := h.performOCR(c.Request.Context(), profile, imageData, input, params)
 	if err != nil {
 		log.Printf("OCR识别失败: %v", err)
-		SendError(c, "OCR识别失败: "+err.Error())
+		SendErrorCode(c, ResponseCode(err), "OCR识别失败: "+err.Error())
 	} else {
-		log.Printf("OCR识别成功: %s", imagePath)
+		log.Printf("OCR识别成功: %s", file.Filename)
 		c.JSON(http.StatusOK, response)
 	}
 }
 
+// bindOcrJson 绑定并验证json请求参数，失败时已写入错误响应
+func bindOcrJson(c *gin.Context, input *OcrDTO) bool {
+	if err := c.ShouldBindWith(input, binding.JSON); err != nil {
+		log.Printf("参数绑定失败: %v", err)
+		SendError(c, "参数格式错误: "+err.Error())
+		return false
+	}
+
+	// 验证输入参数
+	if err := validateOcrDTO(input); err != nil {
+		log.Printf("参数验证失败: %v", err)
+		SendError(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// prepare 解析模型配置和检测参数并读取请求中的图片，失败时已写入错误响应
+func (h *OcrHandler) prepare(c *gin.Context, input *OcrDTO) (*Profile, DetectParams, []byte, bool) {
+	profile, params, err := h.resolve(input)
+	if err != nil {
+		log.Printf("参数验证失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), err.Error())
+		return nil, params, nil, false
+	}
+
+	imageData, err := loadImage(input)
+	if err != nil {
+		log.Printf("图片处理失败: %v", err)
+		SendErrorCode(c, ResponseCode(err), "图片处理失败: "+err.Error())
+		return nil, params, nil, false
+	}
+	return profile, params, imageData, true
+}
+
+// loadImage 解码请求中的base64图片或下载image_url指向的图片
+func loadImage(input *OcrDTO) ([]byte, error) {
+	if input.ImageBase64 != "" {
+		log.Println("处理base64图片")
+		return decodeBase64Image(input.ImageBase64)
+	}
+	log.Printf("下载图片: %s", input.ImageUrl)
+	return downloadImage(input.ImageUrl)
+}
+
+// resolve 选择请求的模型配置，并在该配置的默认参数上应用请求中的检测参数
+func (h *OcrHandler) resolve(input *OcrDTO) (*Profile, DetectParams, error) {
+	profile, err := h.profiles.Get(input.Profile)
+	if err != nil {
+		return nil, DetectParams{}, err
+	}
+	defaults := profile.Detect
+	if input.ReturnImage {
+		defaults.ResultImage = kImageFormatPNG
+		if input.ImageFormat != "" {
+			defaults.ResultImage = input.ImageFormat
+		}
+		defaults.LabelBoxes = input.LabelBoxes
+	}
+	defaults.CharBoxes = input.CharBoxes
+	defaults.TopK = input.TopK
+	params, err := input.DetectOptions.Resolve(defaults)
+	if err != nil {
+		return nil, DetectParams{}, fmt.Errorf("检测参数无效: %w", err)
+	}
+	return profile, params, nil
+}
+
 // performOCR 执行OCR识别的核心逻辑
-func performOCR(imagePath string, input OcrDTO) (*Response, error) {
-	// 确保在函数结束时清理临时文件
-	defer func() {
-		cleanupFiles(imagePath)
-	}()
+func (h *OcrHandler) performOCR(ctx context.Context, profile *Profile, imageData []byte, input OcrDTO, params DetectParams) (*Response, error) {
+	if doc := openDocument(imageData); doc.pageCount > 1 || input.PageRange != "" {
+		return h.performDocumentOCR(ctx, profile, doc, input, params)
+	}
 
-	detect, ocrResult := Detect(imagePath)
-	if !detect {
-		return nil, fmt.Errorf("OCR识别失败")
+	ocrResult, err := recognize(ctx, profile.Engine, imageData, params)
+	if err != nil {
+		return nil, err
 	}
 
 	// 根据需要返回文本块信息
 	if !input.NeedBlock {
 		ocrResult.TextBlocks = nil
 	}
+	if len(ocrResult.Image) > 0 {
+		ocrResult.ImageFormat = params.ResultImage
+	}
 
 	// 如果需要识别二维码
 	if input.QrCode {
-		hasQR, qrContent := DetectQrCodeWithContent(imagePath)
-		ocrResult.QRCode = hasQR
-		if hasQR {
+		qrResult := DetectQRCodeFromBytes(imageData)
+		ocrResult.QRCode = qrResult.Found
+		if qrResult.Found {
 			// 可以在这里添加二维码内容到结果中
-			log.Printf("检测到二维码: %s", qrContent)
+			log.Printf("检测到二维码: %s", qrResult.Content)
 		}
 	}
 
 	return &Response{Code: 200, Msg: "ok", Data: ocrResult}, nil
 }
 
-// cleanupFiles 清理临时文件
-func cleanupFiles(imagePath string) {
-	files := []string{imagePath, imagePath + "-result.jpg"}
-	for _, file := range files {
-		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
-			log.Printf("清理文件失败 %s: %v", file, err)
+// performDocumentOCR 逐页识别多页图片，选择的页数超过MaxPages时不解码直接返回错误
+func (h *OcrHandler) performDocumentOCR(ctx context.Context, profile *Profile, doc *document, input OcrDTO, params DetectParams) (*Response, error) {
+	pages, err := selectPages(input.PageRange, doc.pageCount)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) > h.MaxPages {
+		return nil, fmt.Errorf("%w: 选择了%d页，单次最多识别%d页，请通过page_range分批识别",
+			ErrInvalidArgument, len(pages), h.MaxPages)
+	}
+
+	ocrResult, err := recognizeDocument(ctx, profile.Engine, doc, pages, params, input.QrCode)
+	if err != nil {
+		return nil, err
+	}
+	if !input.NeedBlock {
+		for i := range ocrResult.Pages {
+			ocrResult.Pages[i].TextBlocks = nil
 		}
 	}
+	log.Printf("多页图片识别完成，共%d页，识别%d页", doc.pageCount, len(pages))
+	return &Response{Code: 200, Msg: "ok", Data: ocrResult}, nil
 }
 
-// isValidImageFile 验证文件类型
-func isValidImageFile(filename string) bool {
-	ext := strings.ToLower(filename)
-	return strings.HasSuffix(ext, ".jpg") ||
-		strings.HasSuffix(ext, ".jpeg") ||
-		strings.HasSuffix(ext, ".png")
+// needsGoDecode 图片需要在Go侧解码：引擎不能直接解码的格式、带有EXIF方向，或者设置了预处理步骤
+//
+// 带EXIF方向的图片在Go侧旋转后以像素传给引擎，避免引擎与二维码识别对方向的处理不一致。
+func needsGoDecode(imageData []byte, params DetectParams) bool {
+	return !isEngineNative(imageData) || len(params.Preprocess) > 0 ||
+		exifOrientation(imageData) > kExifOrientationNormal
+}
+
+// recognize 识别已编码的图片，需要时先在Go侧解码、按EXIF方向旋转并预处理，坐标换算回旋转后的原图坐标
+func recognize(ctx context.Context, engine Engine, imageData []byte, params DetectParams) (*OCRResultData, error) {
+	if !needsGoDecode(imageData, params) {
+		result, err := engine.RecognizeBytes(ctx, imageData, params)
+		if err == nil {
+			result.ExifOrientation = exifOrientation(imageData)
+		}
+		return result, err
+	}
+	img, orientation, err := decodeOrientedImage(imageData)
+	if err != nil {
+		return nil, err
+	}
+	return recognizeImage(ctx, engine, img, orientation, params)
+}
+
+// recognizeImage 预处理并识别已解码的图片，orientation为解码时已应用的EXIF方向
+func recognizeImage(ctx context.Context, engine Engine, img image.Image, orientation int, params DetectParams) (*OCRResultData, error) {
+	job := preprocessDecoded(img, orientation, params.Preprocess)
+	result, err := engine.RecognizeImage(ctx, job.img, params)
+	if err != nil {
+		return nil, err
+	}
+	scaleResultBoxes(result.TextBlocks, job.scale)
+	if job.scale > 1 && len(result.Image) > 0 {
+		if result.Image, err = scaleResultImage(result.Image, params.ResultImage, job.size); err != nil {
+			return nil, err
+		}
+	}
+	result.ExifOrientation = job.orientation
+	if len(params.Preprocess) > 0 {
+		result.Preprocess = job.applied
+	}
+	return result, nil
+}
+
+// detect 检测已编码图片的文本框，解码与recognize相同
+func detect(ctx context.Context, engine Engine, imageData []byte, params DetectParams) (*DetectResultData, error) {
+	if !needsGoDecode(imageData, params) {
+		result, err := engine.DetectBytes(ctx, imageData, params)
+		if err == nil {
+			result.ExifOrientation = exifOrientation(imageData)
+		}
+		return result, err
+	}
+	job, err := preprocessImage(imageData, params.Preprocess)
+	if err != nil {
+		return nil, err
+	}
+	result, err := engine.DetectImage(ctx, job.img, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, box := range result.Boxes {
+		scaleBoxPoints(box.BoxPoint, job.scale)
+	}
+	result.ExifOrientation = job.orientation
+	if len(params.Preprocess) > 0 {
+		result.Preprocess = job.applied
+	}
+	return result, nil
 }
 
 func SendError(c *gin.Context, message string) {
-	c.JSON(http.StatusOK, Response{Code: 500, Msg: message, Data: nil})
+	SendErrorCode(c, 500, message)
+}
+
+// SendErrorCode 返回指定code的错误响应
+func SendErrorCode(c *gin.Context, code int, message string) {
+	c.JSON(http.StatusOK, Response{Code: code, Msg: message, Data: nil})
 }
@@ -2,7 +2,13 @@ package src
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"image"
+	"image/gif"
+	"image/png"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -12,6 +18,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/bmp"
 )
 
 func init() {
@@ -124,6 +132,11 @@ func TestIsValidImageFile(t *testing.T) {
 		{"valid jpeg", "test.jpeg", true},
 		{"valid png", "test.png", true},
 		{"valid JPG uppercase", "test.JPG", true},
+		{"valid webp", "test.webp", true},
+		{"valid bmp", "test.bmp", true},
+		{"valid gif", "test.gif", true},
+		{"valid tif", "test.tif", true},
+		{"valid TIFF uppercase", "test.TIFF", true},
 		{"invalid txt", "test.txt", false},
 		{"invalid no extension", "test", false},
 		{"invalid pdf", "test.pdf", false},
@@ -140,7 +153,7 @@ func TestIsValidImageFile(t *testing.T) {
 func TestOcrJsonAPI(t *testing.T) {
 	// 创建测试路由
 	router := gin.New()
-	router.POST("/api/ocr", OcrJson)
+	router.POST("/api/ocr", NewOcrHandler(NewFakeEngine(), DefaultDetectParams()).OcrJson)
 
 	tests := []struct {
 		name           string
@@ -195,7 +208,7 @@ func TestOcrJsonAPI(t *testing.T) {
 func TestOcrFileAPI(t *testing.T) {
 	// 创建测试路由
 	router := gin.New()
-	router.POST("/api/ocr_file", OcrFile)
+	router.POST("/api/ocr_file", NewOcrHandler(NewFakeEngine(), DefaultDetectParams()).OcrFile)
 
 	t.Run("no file uploaded", func(t *testing.T) {
 		req, _ := http.NewRequest("POST", "/api/ocr_file", nil)
@@ -241,21 +254,565 @@ func TestOcrFileAPI(t *testing.T) {
 	})
 }
 
-func TestCleanupFiles(t *testing.T) {
-	// 创建临时测试文件
-	testFile := "test_cleanup.txt"
-	err := os.WriteFile(testFile, []byte("test content"), 0644)
+func TestOcrJsonWithEngine(t *testing.T) {
+	validPNG := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+
+	post := func(engine Engine, payload map[string]interface{}) Response {
+		router := gin.New()
+		router.POST("/api/ocr", NewOcrHandler(engine, DefaultDetectParams()).OcrJson)
+
+		jsonBytes, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/api/ocr", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response Response
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response
+	}
+
+	t.Run("recognize success", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, 1, engine.Calls())
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, []interface{}{"模拟识别结果"}, data["texts"])
+		assert.NotContains(t, data, "text_blocks")
+	})
+
+	t.Run("need block", func(t *testing.T) {
+		response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG, "need_block": true})
+
+		assert.Equal(t, 200, response.Code)
+		data := response.Data.(map[string]interface{})
+		assert.Len(t, data["text_blocks"], 1)
+	})
+
+	t.Run("engine error", func(t *testing.T) {
+		engine := &FakeEngine{Err: errors.New("模型未加载")}
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG})
+
+		assert.Equal(t, 500, response.Code)
+		assert.Contains(t, response.Msg, "模型未加载")
+	})
+
+	t.Run("native error code", func(t *testing.T) {
+		engine := &FakeEngine{Err: &OcrError{Code: ErrCodeDecodeImage, Message: "图片解码失败"}}
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG})
+
+		assert.Equal(t, 5003, response.Code)
+		assert.Contains(t, response.Msg, "图片解码失败")
+	})
+
+	t.Run("image error code", func(t *testing.T) {
+		notImage := base64.StdEncoding.EncodeToString([]byte("not an image"))
+		response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": notImage})
+		assert.Equal(t, 5003, response.Code)
+		assert.Contains(t, response.Msg, "不支持的图片格式")
+
+		large := base64.StdEncoding.EncodeToString(make([]byte, maxFileSize+1))
+		response = post(NewFakeEngine(), map[string]interface{}{"image_base_64": large})
+		assert.Equal(t, 5007, response.Code)
+	})
+
+	t.Run("engine unavailable", func(t *testing.T) {
+		engine := NewUnavailableEngine(errors.New("模型文件不存在"))
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG})
+
+		assert.Equal(t, 5001, response.Code)
+		assert.Contains(t, response.Msg, "模型文件不存在")
+	})
+
+	t.Run("engine closed", func(t *testing.T) {
+		engine := NewFakeEngine()
+		assert.NoError(t, engine.Close())
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG})
+
+		assert.Equal(t, 500, response.Code)
+		assert.Contains(t, response.Msg, ErrEngineClosed.Error())
+	})
+
+	t.Run("detect params", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{
+			"image_base_64": validPNG,
+			"preset":        "fast",
+			"padding":       20,
+			"box_thresh":    0.4,
+		})
+
+		assert.Equal(t, 200, response.Code)
+		params := engine.LastParams()
+		assert.Equal(t, 640, params.MaxSideLen)
+		assert.False(t, params.DoAngle)
+		assert.Equal(t, 20, params.Padding)
+		assert.Equal(t, 0.4, params.BoxThresh)
+		assert.Equal(t, DefaultDetectParams().UnClipRatio, params.UnClipRatio)
+	})
+
+	t.Run("return image", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "return_image": true, "label_boxes": true})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, "png", engine.LastParams().ResultImage)
+		assert.True(t, engine.LastParams().LabelBoxes)
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, "png", data["image_format"])
+		image, err := base64.StdEncoding.DecodeString(data["image"].(string))
+		assert.NoError(t, err)
+		assert.Equal(t, "png", detectImageType(image))
+	})
+
+	t.Run("no image by default", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "image_format": "jpeg"})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Empty(t, engine.LastParams().ResultImage)
+		assert.NotContains(t, response.Data.(map[string]interface{}), "image")
+	})
+
+	t.Run("char boxes", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "need_block": true, "char_boxes": true})
+
+		assert.Equal(t, 200, response.Code)
+		assert.True(t, engine.LastParams().CharBoxes)
+		block := response.Data.(map[string]interface{})["text_blocks"].([]interface{})[0].(map[string]interface{})
+		charBoxes := block["char_boxes"].([]interface{})
+		assert.Len(t, charBoxes, len(block["char_scores"].([]interface{})))
+		assert.Len(t, charBoxes[0].(map[string]interface{})["box_point"], 4)
+	})
+
+	t.Run("top k candidates", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "need_block": true, "top_k": 3})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, 3, engine.LastParams().TopK)
+		block := response.Data.(map[string]interface{})["text_blocks"].([]interface{})[0].(map[string]interface{})
+		charCandidates := block["char_candidates"].([]interface{})
+		assert.Len(t, charCandidates, len(block["char_scores"].([]interface{})))
+		candidates := charCandidates[0].([]interface{})
+		assert.Len(t, candidates, 3)
+		first := candidates[0].(map[string]interface{})
+		second := candidates[1].(map[string]interface{})
+		assert.GreaterOrEqual(t, first["score"], second["score"])
+	})
+
+	t.Run("beam search hotwords", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "beam_width": 5, "hotwords": []string{"营业执照"}})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, 5, engine.LastParams().BeamWidth)
+		assert.Equal(t, []string{"营业执照"}, engine.LastParams().Hotwords)
+		assert.Equal(t, kDefaultHotwordBoost, engine.LastParams().HotwordBoost)
+	})
+
+	t.Run("char whitelist", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "char_whitelist": "0123456789X", "char_sets": []string{"digits"}})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, "0123456789X", engine.LastParams().CharWhitelist)
+		assert.Equal(t, []string{"digits"}, engine.LastParams().CharSets)
+	})
+
+	t.Run("preprocess", func(t *testing.T) {
+		engine := NewFakeEngine()
+		steps := []string{kPreprocessBinarize, kPreprocessUpscale, kPreprocessGrayscale}
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "need_block": true, "preprocess": steps})
+
+		require.Equal(t, 200, response.Code)
+		assert.Equal(t, steps, engine.LastParams().Preprocess)
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, []interface{}{kPreprocessUpscale, kPreprocessGrayscale, kPreprocessBinarize}, data["preprocess"])
+		// 1x1的图片放大4倍后识别，坐标换算回原图
+		block := data["text_blocks"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, float64(3), block["box_point"].([]interface{})[0].(map[string]interface{})["x"])
+
+		// 结果图片缩小回原图尺寸
+		params := DefaultDetectParams()
+		params.Preprocess, params.ResultImage = steps, kImageFormatPNG
+		result, err := recognizeImage(context.Background(), NewFakeEngine(), image.NewGray(image.Rect(0, 0, 1, 1)), 0, params)
+		require.NoError(t, err)
+		img, _, err := image.Decode(bytes.NewReader(result.Image))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), img.Bounds())
+
+		response = post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG, "preprocess": []string{"sharpen"}})
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "未知的预处理步骤")
+	})
+
+	t.Run("exif orientation", func(t *testing.T) {
+		data := jpegWithOrientation(t, image.NewGray(image.Rect(0, 0, 40, 20)), 6)
+		response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": base64.StdEncoding.EncodeToString(data)})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, float64(6), response.Data.(map[string]interface{})["exif_orientation"])
+
+		response = post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG})
+		assert.NotContains(t, response.Data.(map[string]interface{}), "exif_orientation")
+	})
+
+	t.Run("non native formats", func(t *testing.T) {
+		src := image.NewGray(image.Rect(0, 0, 40, 20))
+		var gifData, bmpData bytes.Buffer
+		require.NoError(t, gif.Encode(&gifData, src, nil))
+		require.NoError(t, bmp.Encode(&bmpData, src))
+
+		for _, data := range [][]byte{gifData.Bytes(), bmpData.Bytes()} {
+			response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": base64.StdEncoding.EncodeToString(data)})
+			assert.Equal(t, 200, response.Code)
+		}
+
+		// 文件头正确但无法解码
+		webp := []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x00\x00\x00\x00")
+		response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": base64.StdEncoding.EncodeToString(webp)})
+		assert.Equal(t, 5003, response.Code)
+	})
+
+	t.Run("deskew", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "deskew": true, "deskew_map_back": true})
+
+		assert.Equal(t, 200, response.Code)
+		assert.True(t, engine.LastParams().Deskew)
+		assert.True(t, engine.LastParams().DeskewMapBack)
+		assert.Equal(t, float64(0), response.Data.(map[string]interface{})["skew_angle"])
+	})
+
+	t.Run("auto rotate", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "auto_rotate": true})
+
+		assert.Equal(t, 200, response.Code)
+		assert.True(t, engine.LastParams().AutoRotate)
+		assert.Equal(t, float64(0), response.Data.(map[string]interface{})["page_rotation"])
+	})
+
+	t.Run("invalid top k", func(t *testing.T) {
+		response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG, "top_k": 100})
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "top_k")
+	})
+
+	t.Run("no char boxes by default", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "need_block": true})
+
+		assert.Equal(t, 200, response.Code)
+		assert.False(t, engine.LastParams().CharBoxes)
+		block := response.Data.(map[string]interface{})["text_blocks"].([]interface{})[0].(map[string]interface{})
+		assert.NotContains(t, block, "char_boxes")
+		assert.NotContains(t, block, "char_candidates")
+	})
+
+	t.Run("invalid image format", func(t *testing.T) {
+		response := post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG, "return_image": true, "image_format": "gif"})
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "image_format")
+	})
+
+	t.Run("invalid detect params", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "box_score_thresh": 1.5})
+
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "box_score_thresh")
+		assert.Equal(t, 0, engine.Calls())
+	})
+}
+
+func TestOcrJsonWithProfiles(t *testing.T) {
+	validPNG := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+
+	general := NewFakeEngine()
+	digits := NewFakeEngine()
+	digitsDetect := DefaultDetectParams()
+	digitsDetect.MaxSideLen = 320
+	profiles, err := NewProfileSet("general",
+		&Profile{Name: "general", Engine: general, Detect: DefaultDetectParams()},
+		&Profile{Name: "digits", Engine: digits, Detect: digitsDetect})
 	assert.NoError(t, err)
 
-	// 确保文件存在
-	_, err = os.Stat(testFile)
+	router := gin.New()
+	router.POST("/api/ocr", NewProfileOcrHandler(profiles).OcrJson)
+	post := func(payload map[string]interface{}) Response {
+		jsonBytes, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/api/ocr", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	response := post(map[string]interface{}{"image_base_64": validPNG})
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, 1, general.Calls())
+
+	// 使用所选配置的默认检测参数，请求中的参数仍然可以覆盖
+	response = post(map[string]interface{}{"image_base_64": validPNG, "profile": "digits", "padding": 0})
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, 1, digits.Calls())
+	assert.Equal(t, 320, digits.LastParams().MaxSideLen)
+	assert.Equal(t, 0, digits.LastParams().Padding)
+
+	response = post(map[string]interface{}{"image_base_64": validPNG, "profile": "english"})
+	assert.Equal(t, 5006, response.Code)
+	assert.Contains(t, response.Msg, "english")
+}
+
+func TestOcrJsonMultiPage(t *testing.T) {
+	engine := NewFakeEngine()
+	handler := NewOcrHandler(engine, DefaultDetectParams())
+	handler.MaxPages = 3
+	router := gin.New()
+	router.POST("/api/ocr", handler.OcrJson)
+	post := func(payload map[string]interface{}) Response {
+		jsonBytes, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/api/ocr", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	tiffPages := base64.StdEncoding.EncodeToString(encodeTIFFPages(t,
+		grayPage(30, 10, 255), grayPage(20, 40, 255), grayPage(10, 10, 255), grayPage(10, 10, 255)))
+
+	t.Run("page range", func(t *testing.T) {
+		response := post(map[string]interface{}{"image_base_64": tiffPages, "page_range": "2-3", "need_block": true})
+		require.Equal(t, 200, response.Code)
+
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, float64(4), data["page_count"])
+		assert.NotContains(t, data, "text_blocks")
+		pages := data["pages"].([]interface{})
+		require.Len(t, pages, 2)
+		page := pages[0].(map[string]interface{})
+		assert.Equal(t, float64(2), page["page"])
+		assert.Equal(t, float64(20), page["width"])
+		assert.Equal(t, float64(40), page["height"])
+		assert.NotEmpty(t, page["text_blocks"])
+		assert.Len(t, data["texts"], 2)
+	})
+
+	t.Run("animated gif", func(t *testing.T) {
+		response := post(map[string]interface{}{"image_base_64": base64.StdEncoding.EncodeToString(encodeGIFFrames(t, 3))})
+		require.Equal(t, 200, response.Code)
+		pages := response.Data.(map[string]interface{})["pages"].([]interface{})
+		require.Len(t, pages, 3)
+		assert.NotContains(t, pages[0], "text_blocks")
+	})
+
+	t.Run("page limit", func(t *testing.T) {
+		calls := engine.Calls()
+		response := post(map[string]interface{}{"image_base_64": tiffPages})
+		assert.Equal(t, 5006, response.Code)
+		assert.Contains(t, response.Msg, "page_range")
+		assert.Equal(t, calls, engine.Calls())
+	})
+
+	t.Run("page out of range", func(t *testing.T) {
+		response := post(map[string]interface{}{"image_base_64": tiffPages, "page_range": "5"})
+		assert.Equal(t, 5006, response.Code)
+	})
+}
+
+func TestOcrImageAPI(t *testing.T) {
+	validPNG := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+
+	post := func(engine Engine, payload map[string]interface{}) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/api/ocr_image", NewOcrHandler(engine, DefaultDetectParams()).OcrImage)
+
+		jsonBytes, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/api/ocr_image", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("png", func(t *testing.T) {
+		w := post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "png", detectImageType(w.Body.Bytes()))
+	})
+
+	t.Run("jpeg", func(t *testing.T) {
+		w := post(NewFakeEngine(), map[string]interface{}{"image_base_64": validPNG, "image_format": "jpeg"})
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		assert.Equal(t, "jpg", detectImageType(w.Body.Bytes()))
+	})
+
+	t.Run("engine error", func(t *testing.T) {
+		w := post(NewUnavailableEngine(errors.New("模型文件不存在")), map[string]interface{}{"image_base_64": validPNG})
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 5001, response.Code)
+	})
+}
+
+func TestDetectAPI(t *testing.T) {
+	validPNG := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+
+	post := func(engine Engine, payload map[string]interface{}) Response {
+		router := gin.New()
+		router.POST("/api/detect", NewOcrHandler(engine, DefaultDetectParams()).Detect)
+
+		jsonBytes, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/api/detect", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("boxes only", func(t *testing.T) {
+		engine := NewFakeEngine()
+		response := post(engine, map[string]interface{}{"image_base_64": validPNG, "box_thresh": 0.4})
+
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, 0.4, engine.LastParams().BoxThresh)
+		data := response.Data.(map[string]interface{})
+		assert.NotContains(t, data, "texts")
+		boxes := data["boxes"].([]interface{})
+		assert.Len(t, boxes, 1)
+		box := boxes[0].(map[string]interface{})
+		assert.Equal(t, 0.92, box["box_score"])
+		assert.Len(t, box["box_point"], 4)
+	})
+
+	t.Run("missing image", func(t *testing.T) {
+		response := post(NewFakeEngine(), map[string]interface{}{})
+		assert.Equal(t, 500, response.Code)
+	})
+
+	t.Run("engine unavailable", func(t *testing.T) {
+		response := post(NewUnavailableEngine(errors.New("dbnet模型文件不存在")), map[string]interface{}{"image_base_64": validPNG})
+		assert.Equal(t, 5001, response.Code)
+		assert.Contains(t, response.Msg, "dbnet模型文件不存在")
+	})
+}
+
+func TestRecognizeLinesAPI(t *testing.T) {
+	validPNG := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+
+	engine := NewFakeEngine()
+	router := gin.New()
+	router.POST("/api/recognize_lines", NewOcrHandler(engine, DefaultDetectParams()).RecognizeLines)
+	post := func(payload map[string]interface{}) Response {
+		jsonBytes, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/api/recognize_lines", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		response := post(map[string]interface{}{"images_base_64": []string{validPNG, validPNG}})
+
+		assert.Equal(t, 200, response.Code)
+		// 默认不进行方向检测
+		assert.False(t, engine.LastParams().DoAngle)
+		data := response.Data.(map[string]interface{})
+		assert.Len(t, data["texts"], 2)
+		blocks := data["text_blocks"].([]interface{})
+		assert.Len(t, blocks, 2)
+		assert.Len(t, blocks[0].(map[string]interface{})["char_scores"], 4)
+	})
+
+	t.Run("vertical", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 20, 80))))
+		tallPNG := base64.StdEncoding.EncodeToString(buf.Bytes())
+		response := post(map[string]interface{}{"images_base_64": []string{validPNG, tallPNG}})
+
+		assert.Equal(t, 200, response.Code)
+		blocks := response.Data.(map[string]interface{})["text_blocks"].([]interface{})
+		assert.Equal(t, false, blocks[0].(map[string]interface{})["vertical"])
+		assert.Equal(t, true, blocks[1].(map[string]interface{})["vertical"])
+	})
+
+	t.Run("do angle", func(t *testing.T) {
+		response := post(map[string]interface{}{"images_base_64": []string{validPNG}, "do_angle": true})
+		assert.Equal(t, 200, response.Code)
+		assert.True(t, engine.LastParams().DoAngle)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		response := post(map[string]interface{}{"images_base_64": []string{}})
+		assert.Equal(t, 500, response.Code)
+	})
+
+	t.Run("undecodable image", func(t *testing.T) {
+		// PNG文件头之后的数据损坏
+		broken := base64.StdEncoding.EncodeToString([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0, 0, 0, 0})
+		response := post(map[string]interface{}{"images_base_64": []string{validPNG, broken}})
+		assert.Equal(t, 5003, response.Code)
+		assert.Contains(t, response.Msg, "第2张")
+	})
+}
+
+func TestOcrFileWithEngine(t *testing.T) {
+	pngData, err := base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg==")
+	assert.NoError(t, err)
+
+	engine := NewFakeEngine()
+	router := gin.New()
+	router.POST("/api/ocr_file", NewOcrHandler(engine, DefaultDetectParams()).OcrFile)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "test.png")
 	assert.NoError(t, err)
+	_, err = part.Write(pngData)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.WriteField("need_block", "true"))
+	assert.NoError(t, writer.WriteField("preset", "small-text"))
+	assert.NoError(t, writer.WriteField("do_angle", "false"))
+	writer.Close()
 
-	// 调用清理函数
-	cleanupFiles(testFile)
+	req, _ := http.NewRequest("POST", "/api/ocr_file", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
 
-	// 验证文件已被删除
-	_, err = os.Stat(testFile)
+	var response Response
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, 1, engine.Calls())
+	assert.Equal(t, 0, engine.LastParams().MaxSideLen)
+	assert.False(t, engine.LastParams().DoAngle)
+	data := response.Data.(map[string]interface{})
+	assert.Len(t, data["text_blocks"], 1)
+
+	// 识别过程不再写入临时文件
+	_, err = os.Stat("tmp")
 	assert.True(t, os.IsNotExist(err))
 }
 
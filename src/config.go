@@ -0,0 +1,189 @@
+package src
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+const (
+	defaultMaxPoolSize = 4
+	defaultQueueSize   = 64
+)
+
+// Config 服务配置，通过环境变量加载
+type Config struct {
+	// PoolSize OCR实例数量，每个实例独立加载模型，可以并发识别
+	PoolSize int
+	// QueueSize 所有实例都忙时允许排队等待的请求数，超出后直接返回繁忙
+	QueueSize int
+	// ThreadNum 每个实例使用的线程数
+	ThreadNum int
+	// Detect 请求未指定时使用的检测参数
+	Detect DetectParams
+	// ModelManifest 模型清单文件路径
+	ModelManifest string
+	// Profiles 模型配置文件路径，配置后按文件加载多套模型，ModelManifest不再使用
+	Profiles string
+	// RequireModels 模型校验失败时拒绝启动，否则服务继续运行但识别接口不可用
+	RequireModels bool
+	// AdminToken 管理接口的访问令牌，为空时不开放管理接口
+	AdminToken string
+	// MaxPages 多页TIFF、GIF单次请求最多识别的页数
+	MaxPages int
+}
+
+// DefaultConfig 返回默认配置，实例数量和线程数根据CPU核数计算
+func DefaultConfig() Config {
+	poolSize := runtime.NumCPU() / 2
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	if poolSize > defaultMaxPoolSize {
+		poolSize = defaultMaxPoolSize
+	}
+	return Config{
+		PoolSize:  poolSize,
+		QueueSize: defaultQueueSize,
+		ThreadNum: threadsPerInstance(poolSize),
+		Detect:    DefaultDetectParams(),
+		MaxPages:  kDefaultMaxPages,
+
+		ModelManifest: kDefaultModelManifest,
+	}
+}
+
+// LoadConfig 从环境变量加载配置，未设置或无效的值使用默认配置
+//
+//	OCR_POOL_SIZE   OCR实例数量
+//	OCR_QUEUE_SIZE  等待队列长度
+//	OCR_THREAD_NUM  每个实例的线程数，默认按CPU核数平均分配
+//	OCR_MODEL_MANIFEST  模型清单文件路径
+//	OCR_PROFILES        模型配置文件路径，用于同时加载多套模型
+//	OCR_REQUIRE_MODELS  模型校验失败时是否拒绝启动
+//	OCR_ADMIN_TOKEN     管理接口的访问令牌
+//	OCR_MAX_PAGES       多页图片单次请求最多识别的页数
+//
+// 默认检测参数:
+//
+//	OCR_DETECT_PRESET     参数预设（fast、accurate、small-text）
+//	OCR_PADDING           图片四周补白的像素数
+//	OCR_MAX_SIDE_LEN      缩放后长边的最大长度，0表示不缩放
+//	OCR_BOX_SCORE_THRESH  文本框置信度阈值
+//	OCR_BOX_THRESH        文本区域二值化阈值
+//	OCR_UNCLIP_RATIO      文本框扩张比例
+//	OCR_DO_ANGLE          是否进行文字方向检测
+//	OCR_MOST_ANGLE        是否按多数文本块的方向统一方向
+func LoadConfig() Config {
+	config := DefaultConfig()
+
+	if size, ok := envInt("OCR_POOL_SIZE", 1); ok {
+		config.PoolSize = size
+		config.ThreadNum = threadsPerInstance(size)
+	}
+	if size, ok := envInt("OCR_QUEUE_SIZE", 0); ok {
+		config.QueueSize = size
+	}
+	if num, ok := envInt("OCR_THREAD_NUM", 1); ok {
+		config.ThreadNum = num
+	}
+	if path := os.Getenv("OCR_MODEL_MANIFEST"); path != "" {
+		config.ModelManifest = path
+	}
+	config.Profiles = os.Getenv("OCR_PROFILES")
+	if b, ok := envBool("OCR_REQUIRE_MODELS"); ok {
+		config.RequireModels = b
+	}
+	config.AdminToken = os.Getenv("OCR_ADMIN_TOKEN")
+	if pages, ok := envInt("OCR_MAX_PAGES", 1); ok {
+		config.MaxPages = pages
+	}
+	config.Detect = loadDetectParams(config.Detect)
+
+	return config
+}
+
+// loadDetectParams 从环境变量加载默认检测参数，参数组合无效时使用defaults
+func loadDetectParams(defaults DetectParams) DetectParams {
+	options := DetectOptions{Preset: os.Getenv("OCR_DETECT_PRESET")}
+	if n, ok := envInt("OCR_PADDING", 0); ok {
+		options.Padding = &n
+	}
+	if n, ok := envInt("OCR_MAX_SIDE_LEN", 0); ok {
+		options.MaxSideLen = &n
+	}
+	if f, ok := envFloat("OCR_BOX_SCORE_THRESH"); ok {
+		options.BoxScoreThresh = &f
+	}
+	if f, ok := envFloat("OCR_BOX_THRESH"); ok {
+		options.BoxThresh = &f
+	}
+	if f, ok := envFloat("OCR_UNCLIP_RATIO"); ok {
+		options.UnClipRatio = &f
+	}
+	if b, ok := envBool("OCR_DO_ANGLE"); ok {
+		options.DoAngle = &b
+	}
+	if b, ok := envBool("OCR_MOST_ANGLE"); ok {
+		options.MostAngle = &b
+	}
+
+	params, err := options.Resolve(defaults)
+	if err != nil {
+		log.Printf("默认检测参数无效: %v，使用内置默认值", err)
+		return defaults
+	}
+	return params
+}
+
+// threadsPerInstance 将CPU核数平均分配给每个实例
+func threadsPerInstance(poolSize int) int {
+	threadNum := runtime.NumCPU() / poolSize
+	if threadNum < 1 {
+		threadNum = 1
+	}
+	return threadNum
+}
+
+// envInt 读取整数环境变量，小于min的值视为无效
+func envInt(key string, min int) (int, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		log.Printf("环境变量%s的值无效: %s，使用默认值", key, value)
+		return 0, false
+	}
+	return n, true
+}
+
+// envFloat 读取浮点数环境变量
+func envFloat(key string) (float64, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("环境变量%s的值无效: %s，使用默认值", key, value)
+		return 0, false
+	}
+	return f, true
+}
+
+// envBool 读取布尔环境变量
+func envBool(key string) (bool, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return false, false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("环境变量%s的值无效: %s，使用默认值", key, value)
+		return false, false
+	}
+	return b, true
+}
@@ -0,0 +1,87 @@
+package src
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	config := DefaultConfig()
+
+	assert.GreaterOrEqual(t, config.PoolSize, 1)
+	assert.LessOrEqual(t, config.PoolSize, defaultMaxPoolSize)
+	assert.GreaterOrEqual(t, config.ThreadNum, 1)
+	assert.Equal(t, defaultQueueSize, config.QueueSize)
+	assert.Equal(t, DefaultDetectParams(), config.Detect)
+	assert.Equal(t, kDefaultMaxPages, config.MaxPages)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("from env", func(t *testing.T) {
+		os.Setenv("OCR_POOL_SIZE", "3")
+		os.Setenv("OCR_QUEUE_SIZE", "0")
+		os.Setenv("OCR_THREAD_NUM", "2")
+		os.Setenv("OCR_MODEL_MANIFEST", "/models/v2/manifest.json")
+		os.Setenv("OCR_REQUIRE_MODELS", "true")
+		os.Setenv("OCR_PROFILES", "/models/profiles.json")
+		os.Setenv("OCR_MAX_PAGES", "5")
+		defer func() {
+			os.Unsetenv("OCR_POOL_SIZE")
+			os.Unsetenv("OCR_QUEUE_SIZE")
+			os.Unsetenv("OCR_THREAD_NUM")
+			os.Unsetenv("OCR_MODEL_MANIFEST")
+			os.Unsetenv("OCR_REQUIRE_MODELS")
+			os.Unsetenv("OCR_PROFILES")
+			os.Unsetenv("OCR_MAX_PAGES")
+		}()
+
+		config := LoadConfig()
+		assert.Equal(t, 3, config.PoolSize)
+		assert.Equal(t, 0, config.QueueSize)
+		assert.Equal(t, 2, config.ThreadNum)
+		assert.Equal(t, "/models/v2/manifest.json", config.ModelManifest)
+		assert.True(t, config.RequireModels)
+		assert.Equal(t, "/models/profiles.json", config.Profiles)
+		assert.Equal(t, 5, config.MaxPages)
+	})
+
+	t.Run("invalid values fall back to defaults", func(t *testing.T) {
+		os.Setenv("OCR_POOL_SIZE", "0")
+		os.Setenv("OCR_QUEUE_SIZE", "abc")
+		defer func() {
+			os.Unsetenv("OCR_POOL_SIZE")
+			os.Unsetenv("OCR_QUEUE_SIZE")
+		}()
+
+		config := LoadConfig()
+		defaults := DefaultConfig()
+		assert.Equal(t, defaults.PoolSize, config.PoolSize)
+		assert.Equal(t, defaults.QueueSize, config.QueueSize)
+	})
+
+	t.Run("detect params from env", func(t *testing.T) {
+		os.Setenv("OCR_DETECT_PRESET", "accurate")
+		os.Setenv("OCR_PADDING", "10")
+		os.Setenv("OCR_MOST_ANGLE", "false")
+		defer func() {
+			os.Unsetenv("OCR_DETECT_PRESET")
+			os.Unsetenv("OCR_PADDING")
+			os.Unsetenv("OCR_MOST_ANGLE")
+		}()
+
+		config := LoadConfig()
+		assert.Equal(t, 1600, config.Detect.MaxSideLen)
+		assert.Equal(t, 10, config.Detect.Padding)
+		assert.False(t, config.Detect.MostAngle)
+	})
+
+	t.Run("invalid detect params fall back to defaults", func(t *testing.T) {
+		os.Setenv("OCR_UNCLIP_RATIO", "9")
+		defer os.Unsetenv("OCR_UNCLIP_RATIO")
+
+		config := LoadConfig()
+		assert.Equal(t, DefaultDetectParams(), config.Detect)
+	})
+}
@@ -0,0 +1,363 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/tiff"
+)
+
+// kDefaultMaxPages 单次请求默认最多识别的页数
+const kDefaultMaxPages = 20
+
+// document 多页TIFF、多帧GIF等多页图片，其他图片视为只有一页
+//
+// 打开时只解析文件结构统计页数，不解码像素，识别时只解码选择的页。
+type document struct {
+	pageCount int
+	// decode 依次解码pages中的页（从0开始，升序）并调用fn，orientation为解码时已应用的EXIF方向
+	decode func(pages []int, fn func(page int, img image.Image, orientation int) error) error
+}
+
+// openDocument 解析图片的页数，无法解析页结构的图片视为只有一页
+func openDocument(data []byte) *document {
+	format := findInputFormat(data)
+	if format != nil && format.name == "tiff" {
+		if offsets := tiffPageOffsets(data); len(offsets) > 1 {
+			return &document{pageCount: len(offsets), decode: func(pages []int, fn func(int, image.Image, int) error) error {
+				return decodeTIFFPages(data, offsets, pages, fn)
+			}}
+		}
+	}
+	if format != nil && format.name == "gif" {
+		if ends := gifFrameEnds(data); len(ends) > 1 {
+			return &document{pageCount: len(ends), decode: func(pages []int, fn func(int, image.Image, int) error) error {
+				return decodeGIFPages(data, ends, pages, fn)
+			}}
+		}
+	}
+	return &document{pageCount: 1, decode: func(pages []int, fn func(int, image.Image, int) error) error {
+		img, orientation, err := decodeOrientedImage(data)
+		if err != nil {
+			return err
+		}
+		return fn(0, img, orientation)
+	}}
+}
+
+// selectPages 解析page_range，返回从0开始的升序页码，为空时选择所有页
+//
+// page_range为逗号分隔的页码或页码范围，页码从1开始，例如"1-3,5,8-"，"8-"表示第8页到最后一页。
+func selectPages(pageRange string, pageCount int) ([]int, error) {
+	if strings.TrimSpace(pageRange) == "" {
+		pages := make([]int, pageCount)
+		for i := range pages {
+			pages[i] = i
+		}
+		return pages, nil
+	}
+
+	selected := make([]bool, pageCount)
+	for _, item := range strings.Split(pageRange, ",") {
+		item = strings.TrimSpace(item)
+		first, last, isRange := strings.Cut(item, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		end := start
+		if err == nil && isRange {
+			end = pageCount
+			if last = strings.TrimSpace(last); last != "" {
+				end, err = strconv.Atoi(last)
+			}
+		}
+		if err != nil || start < 1 || end < start {
+			return nil, fmt.Errorf("%w: page_range格式错误: %s", ErrInvalidArgument, item)
+		}
+		if end > pageCount {
+			return nil, fmt.Errorf("%w: 页码%d超出范围，图片共%d页", ErrInvalidArgument, end, pageCount)
+		}
+		for page := start; page <= end; page++ {
+			selected[page-1] = true
+		}
+	}
+
+	var pages []int
+	for i, ok := range selected {
+		if ok {
+			pages = append(pages, i)
+		}
+	}
+	return pages, nil
+}
+
+// recognizeDocument 逐页识别pages中的页，每页的坐标为该页的图片坐标，qrCode为true时每页分别检测二维码
+func recognizeDocument(ctx context.Context, engine Engine, doc *document, pages []int, params DetectParams, qrCode bool) (*OCRResultData, error) {
+	result := &OCRResultData{
+		SchemaVersion: ResultSchemaVersion,
+		Texts:         []string{},
+		PageCount:     doc.pageCount,
+		Pages:         make([]OCRPage, 0, len(pages)),
+	}
+	err := doc.decode(pages, func(index int, img image.Image, orientation int) error {
+		pageResult, err := recognizeImage(ctx, engine, img, orientation, params)
+		if err != nil {
+			return fmt.Errorf("第%d页: %w", index+1, err)
+		}
+		bounds := img.Bounds()
+		page := OCRPage{
+			Page:         index + 1,
+			Width:        bounds.Dx(),
+			Height:       bounds.Dy(),
+			PageRotation: pageResult.PageRotation,
+			SkewAngle:    pageResult.SkewAngle,
+			TextBlocks:   pageResult.TextBlocks,
+			Texts:        pageResult.Texts,
+			Image:        pageResult.Image,
+			Preprocess:   pageResult.Preprocess,
+		}
+		if qrCode {
+			page.QRCode = DetectQRCodeFromImage(img).Found
+		}
+
+		result.SchemaVersion = pageResult.SchemaVersion
+		result.DBNetTime += pageResult.DBNetTime
+		result.DetectTime += pageResult.DetectTime
+		result.Texts = append(result.Texts, pageResult.Texts...)
+		result.QRCode = result.QRCode || page.QRCode
+		result.ExifOrientation = pageResult.ExifOrientation
+		if len(page.Image) > 0 {
+			result.ImageFormat = params.ResultImage
+		}
+		result.Pages = append(result.Pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// tiffPageOffsets 返回TIFF中每一页IFD的偏移，文件头无效时返回nil
+func tiffPageOffsets(data []byte) []uint32 {
+	if len(data) < 8 {
+		return nil
+	}
+	var order binary.ByteOrder
+	switch string(data[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	var offsets []uint32
+	visited := make(map[uint32]bool)
+	for offset := order.Uint32(data[4:8]); offset != 0 && !visited[offset]; {
+		visited[offset] = true
+		if int64(offset)+2 > int64(len(data)) {
+			break
+		}
+		// IFD为2字节的条目数、每个12字节的条目和4字节的下一个IFD偏移
+		next := int64(offset) + 2 + int64(order.Uint16(data[offset:]))*12
+		if next+4 > int64(len(data)) {
+			break
+		}
+		offsets = append(offsets, offset)
+		offset = order.Uint32(data[next:])
+	}
+	return offsets
+}
+
+// tiffPage 将文件头中第一个IFD的偏移替换为指定页的IFD，tiff.Decode只解码第一个IFD
+type tiffPage struct {
+	data   []byte
+	header [8]byte
+}
+
+func newTIFFPage(data []byte, offset uint32) *tiffPage {
+	page := &tiffPage{data: data}
+	copy(page.header[:], data[:8])
+	if string(data[:2]) == "II" {
+		binary.LittleEndian.PutUint32(page.header[4:], offset)
+	} else {
+		binary.BigEndian.PutUint32(page.header[4:], offset)
+	}
+	return page
+}
+
+func (p *tiffPage) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(p.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, p.data[off:])
+	if off < int64(len(p.header)) {
+		copy(b, p.header[off:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// decodeTIFFPages 只解码pages中的页
+func decodeTIFFPages(data []byte, offsets []uint32, pages []int, fn func(int, image.Image, int) error) error {
+	for _, page := range pages {
+		config, err := tiff.DecodeConfig(io.NewSectionReader(newTIFFPage(data, offsets[page]), 0, int64(len(data))))
+		if err != nil {
+			return fmt.Errorf("%w: 第%d页: %v", ErrImageDecode, page+1, err)
+		}
+		if err := checkImageSize(config.Width, config.Height); err != nil {
+			return fmt.Errorf("第%d页: %w", page+1, err)
+		}
+		img, err := tiff.Decode(io.NewSectionReader(newTIFFPage(data, offsets[page]), 0, int64(len(data))))
+		if err != nil {
+			return fmt.Errorf("%w: 第%d页: %v", ErrImageDecode, page+1, err)
+		}
+		if err := fn(page, img, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gifFrameEnds 返回GIF中每一帧图像数据结束的位置，文件结构无效时返回已解析的帧
+func gifFrameEnds(data []byte) []int {
+	i := gifHeaderEnd(data)
+	if i < 0 {
+		return nil
+	}
+
+	var ends []int
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // 扩展块：标签之后是数据子块
+			i = skipGIFSubBlocks(data, i+2)
+		case 0x2C: // 图像描述符共10字节，之后是可选的局部颜色表、LZW最小码长和数据子块
+			if i+10 > len(data) {
+				return ends
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i = skipGIFSubBlocks(data, i+1)
+			if i < 0 {
+				return ends
+			}
+			ends = append(ends, i)
+		default: // 0x3B为文件结束，其他为无效数据
+			return ends
+		}
+		if i < 0 {
+			return ends
+		}
+	}
+	return ends
+}
+
+// gifHeaderEnd 返回文件头、逻辑屏幕描述符和全局颜色表之后第一个块的位置，数据不完整时返回-1
+func gifHeaderEnd(data []byte) int {
+	if len(data) < 13 {
+		return -1
+	}
+	// 文件头和逻辑屏幕描述符共13字节，之后是可选的全局颜色表
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+	if i > len(data) {
+		return -1
+	}
+	return i
+}
+
+// skipGIFSubBlocks 跳过从i开始以长度0结束的数据子块，数据不完整时返回-1
+func skipGIFSubBlocks(data []byte, i int) int {
+	for i >= 0 && i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// decodeGIFPages 按处置方法合成pages中每一帧完整的画面，逐帧解码到最后一个需要的帧，不保留已合成的帧
+//
+// 每一帧都不能超出逻辑屏幕（解码时检查），因此按逻辑屏幕的尺寸检查单页像素数，
+// 并按需要合成的帧数检查总像素数，避免大量高压缩率的帧耗尽内存和CPU。
+func decodeGIFPages(data []byte, ends []int, pages []int, fn func(int, image.Image, int) error) error {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrImageDecode, err)
+	}
+	if err := checkImageSize(config.Width, config.Height); err != nil {
+		return err
+	}
+	last := pages[len(pages)-1]
+	if int64(last+1)*int64(config.Width)*int64(config.Height) > kMaxDecodePixels {
+		return fmt.Errorf("%w: 识别第%d页需要合成%d帧%dx%d的画面，超过%d像素",
+			ErrImageTooLarge, last+1, last+1, config.Width, config.Height, kMaxDecodePixels)
+	}
+
+	// 每一帧与文件头拼接为只有该帧的GIF单独解码，帧之前的扩展块（包括处置方法）属于该帧
+	header := data[:gifHeaderEnd(data)]
+	start := len(header)
+	frameData := make([]byte, 0, len(header)+1)
+	canvas := image.NewNRGBA(image.Rect(0, 0, config.Width, config.Height))
+	next := 0
+	for i := 0; i <= last; i++ {
+		frameData = append(append(append(frameData[:0], header...), data[start:ends[i]]...), 0x3B)
+		start = ends[i]
+		g, err := gif.DecodeAll(bytes.NewReader(frameData))
+		if err != nil {
+			return fmt.Errorf("%w: 第%d帧: %v", ErrImageDecode, i+1, err)
+		}
+		if len(g.Image) != 1 {
+			return fmt.Errorf("%w: 第%d帧结构无效", ErrImageDecode, i+1)
+		}
+		frame := g.Image[0]
+		var disposal byte
+		if len(g.Disposal) > 0 {
+			disposal = g.Disposal[0]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(canvas)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		if next < len(pages) && pages[next] == i {
+			if err := fn(i, cloneNRGBA(canvas), 0); err != nil {
+				return err
+			}
+			next++
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return nil
+}
+
+func cloneNRGBA(src *image.NRGBA) *image.NRGBA {
+	dst := image.NewNRGBA(src.Rect)
+	copy(dst.Pix, src.Pix)
+	return dst
+}
@@ -0,0 +1,237 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeTIFFPages 编码未压缩的多页8位灰度TIFF，image/tiff只能编码单页
+func encodeTIFFPages(t *testing.T, pages ...*image.Gray) []byte {
+	t.Helper()
+	const entries = 8
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+	for i, page := range pages {
+		w, h := page.Rect.Dx(), page.Rect.Dy()
+		ifdEnd := buf.Len() + 2 + entries*12 + 4
+		next := uint32(0)
+		if i < len(pages)-1 {
+			next = uint32(ifdEnd + w*h)
+		}
+		binary.Write(&buf, binary.LittleEndian, uint16(entries))
+		for _, entry := range [][2]uint32{
+			{256, uint32(w)},      // ImageWidth
+			{257, uint32(h)},      // ImageLength
+			{258, 8},              // BitsPerSample
+			{259, 1},              // Compression: 不压缩
+			{262, 1},              // PhotometricInterpretation: 黑色为0
+			{273, uint32(ifdEnd)}, // StripOffsets
+			{278, uint32(h)},      // RowsPerStrip
+			{279, uint32(w * h)},  // StripByteCounts
+		} {
+			binary.Write(&buf, binary.LittleEndian, uint16(entry[0]))
+			binary.Write(&buf, binary.LittleEndian, uint16(4)) // LONG
+			binary.Write(&buf, binary.LittleEndian, uint32(1))
+			binary.Write(&buf, binary.LittleEndian, entry[1])
+		}
+		binary.Write(&buf, binary.LittleEndian, next)
+		buf.Write(page.Pix)
+	}
+	return buf.Bytes()
+}
+
+func grayPage(w, h int, v uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = v
+	}
+	return img
+}
+
+// encodeGIFFrames 编码多帧GIF，第一帧为整个画面，其余帧只覆盖左上角
+func encodeGIFFrames(t *testing.T, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.White, color.Black, color.Gray{Y: 128}}
+	anim := &gif.GIF{Config: image.Config{Width: 40, Height: 20, ColorModel: palette}}
+	for i := 0; i < frames; i++ {
+		rect := image.Rect(0, 0, 40, 20)
+		if i > 0 {
+			rect = image.Rect(0, 0, 10, 10)
+		}
+		frame := image.NewPaletted(rect, palette)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i % 2)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, anim))
+	return buf.Bytes()
+}
+
+// collectPages 解码pages中的页，返回页码与图片
+func collectPages(t *testing.T, doc *document, pages []int) map[int]image.Image {
+	t.Helper()
+	images := make(map[int]image.Image)
+	require.NoError(t, doc.decode(pages, func(page int, img image.Image, orientation int) error {
+		images[page] = img
+		return nil
+	}))
+	return images
+}
+
+func TestOpenDocument(t *testing.T) {
+	t.Run("multi page tiff", func(t *testing.T) {
+		data := encodeTIFFPages(t, grayPage(30, 10, 0), grayPage(20, 40, 128), grayPage(50, 50, 255))
+		doc := openDocument(data)
+		require.Equal(t, 3, doc.pageCount)
+
+		images := collectPages(t, doc, []int{1, 2})
+		require.Len(t, images, 2)
+		assert.Equal(t, image.Rect(0, 0, 20, 40), images[1].Bounds())
+		assert.Equal(t, color.Gray{Y: 128}, color.GrayModel.Convert(images[1].At(5, 5)))
+		assert.Equal(t, image.Rect(0, 0, 50, 50), images[2].Bounds())
+
+		// 单页识别的路径只看到第一页
+		img, err := decodeImage(data)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 30, 10), img.Bounds())
+	})
+
+	t.Run("animated gif", func(t *testing.T) {
+		doc := openDocument(encodeGIFFrames(t, 4))
+		require.Equal(t, 4, doc.pageCount)
+
+		images := collectPages(t, doc, []int{0, 1, 2})
+		require.Len(t, images, 3)
+		for _, img := range images {
+			assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+		}
+		// 后续帧只覆盖左上角，其余部分保留第一帧的画面
+		assert.Equal(t, color.Gray{Y: 0}, color.GrayModel.Convert(images[1].At(5, 5)))
+		assert.Equal(t, color.Gray{Y: 255}, color.GrayModel.Convert(images[1].At(30, 15)))
+		assert.Equal(t, color.Gray{Y: 255}, color.GrayModel.Convert(images[2].At(5, 5)))
+		// 帧数很多时逐帧解码合成，只返回选择的页
+		doc = openDocument(encodeGIFFrames(t, 1000))
+		require.Equal(t, 1000, doc.pageCount)
+		images = collectPages(t, doc, []int{998, 999})
+		require.Len(t, images, 2)
+		assert.Equal(t, color.Gray{Y: 255}, color.GrayModel.Convert(images[998].At(5, 5)))
+		assert.Equal(t, color.Gray{Y: 0}, color.GrayModel.Convert(images[999].At(5, 5)))
+		assert.Equal(t, color.Gray{Y: 255}, color.GrayModel.Convert(images[999].At(30, 15)))
+	})
+
+	t.Run("single page", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, grayPage(8, 8, 0)))
+		doc := openDocument(buf.Bytes())
+		assert.Equal(t, 1, doc.pageCount)
+		assert.Len(t, collectPages(t, doc, []int{0}), 1)
+
+		assert.Equal(t, 1, openDocument(encodeTIFFPages(t, grayPage(8, 8, 0))).pageCount)
+		assert.Equal(t, 1, openDocument(encodeGIFFrames(t, 1)).pageCount)
+	})
+
+	t.Run("page too large", func(t *testing.T) {
+		decodeErr := func(doc *document, pages []int) (decoded []int, err error) {
+			err = doc.decode(pages, func(page int, img image.Image, orientation int) error {
+				decoded = append(decoded, page)
+				return nil
+			})
+			return decoded, err
+		}
+
+		// 第二页的ImageWidth、ImageLength改为100000，解码前按文件头拒绝
+		data := encodeTIFFPages(t, grayPage(4, 4, 0), grayPage(4, 4, 0))
+		offset := tiffPageOffsets(data)[1]
+		binary.LittleEndian.PutUint32(data[offset+2+8:], 100000)
+		binary.LittleEndian.PutUint32(data[offset+2+12+8:], 100000)
+		decoded, err := decodeErr(openDocument(data), []int{0, 1})
+		assert.ErrorIs(t, err, ErrImageTooLarge)
+		assert.Contains(t, err.Error(), "第2页")
+		assert.Equal(t, []int{0}, decoded)
+
+		// 逻辑屏幕为65535x65535的GIF
+		data = encodeGIFFrames(t, 2)
+		binary.LittleEndian.PutUint16(data[6:], 0xFFFF)
+		binary.LittleEndian.PutUint16(data[8:], 0xFFFF)
+		decoded, err = decodeErr(openDocument(data), []int{0})
+		assert.ErrorIs(t, err, ErrImageTooLarge)
+		assert.Empty(t, decoded)
+
+		_, err = decodeImage(data)
+		assert.ErrorIs(t, err, ErrImageTooLarge)
+
+		// 单页不超过限制，但识别第12页需要合成12帧7000x7000的画面，解码前拒绝
+		data = encodeGIFFrames(t, 12)
+		binary.LittleEndian.PutUint16(data[6:], 7000)
+		binary.LittleEndian.PutUint16(data[8:], 7000)
+		decoded, err = decodeErr(openDocument(data), []int{11})
+		assert.ErrorIs(t, err, ErrImageTooLarge)
+		assert.Empty(t, decoded)
+	})
+
+	t.Run("tiff ifd loop", func(t *testing.T) {
+		data := encodeTIFFPages(t, grayPage(4, 4, 0), grayPage(4, 4, 0))
+		// 第二页的下一个IFD指回第一页
+		binary.LittleEndian.PutUint32(data[len(data)-16-4:], 8)
+		assert.Len(t, tiffPageOffsets(data), 2)
+	})
+}
+
+func TestSelectPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		pageRange string
+		expected  []int
+		expectErr bool
+	}{
+		{"all pages", "", []int{0, 1, 2, 3, 4}, false},
+		{"single page", "2", []int{1}, false},
+		{"range", "2-4", []int{1, 2, 3}, false},
+		{"open range", "4-", []int{3, 4}, false},
+		{"merged and sorted", "5, 1-2,2", []int{0, 1, 4}, false},
+		{"out of range", "3-6", nil, true},
+		{"zero", "0", nil, true},
+		{"reversed", "3-1", nil, true},
+		{"not a number", "a", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := selectPages(tt.pageRange, 5)
+			if tt.expectErr {
+				assert.ErrorIs(t, err, ErrInvalidArgument)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, pages)
+		})
+	}
+}
+
+func TestRecognizeDocument(t *testing.T) {
+	doc := openDocument(encodeTIFFPages(t, grayPage(30, 10, 255), grayPage(20, 40, 255)))
+	result, err := recognizeDocument(context.Background(), NewFakeEngine(), doc, []int{0, 1}, DefaultDetectParams(), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.PageCount)
+	require.Len(t, result.Pages, 2)
+	assert.Equal(t, 2, result.Pages[1].Page)
+	assert.Equal(t, 20, result.Pages[1].Width)
+	assert.Equal(t, 40, result.Pages[1].Height)
+	assert.Equal(t, append(result.Pages[0].Texts, result.Pages[1].Texts...), result.Texts)
+	assert.Empty(t, result.TextBlocks)
+}
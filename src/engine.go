@@ -0,0 +1,180 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+)
+
+// ErrEngineClosed 引擎已关闭
+var ErrEngineClosed = errors.New("OCR引擎已关闭")
+
+// Engine OCR识别引擎
+//
+// 不同的后端（cgo OcrLite、测试用的模拟引擎等）实现该接口，
+// 接口处理器只依赖该接口，便于作为库嵌入以及在运行时替换引擎。
+type Engine interface {
+	// RecognizeBytes 使用指定的检测参数识别内存中已编码的图片（jpg、png、webp、bmp、gif、tiff）
+	RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error)
+	// RecognizeImage 使用指定的检测参数识别已解码的图片
+	RecognizeImage(ctx context.Context, img image.Image, params DetectParams) (*OCRResultData, error)
+	// RecognizeLines 跳过文本检测，将每张图片作为一个文本行识别，结果的TextBlocks与lines一一对应
+	//
+	// params中只有方向检测相关的参数生效。
+	RecognizeLines(ctx context.Context, lines []image.Image, params DetectParams) (*OCRResultData, error)
+	// DetectBytes 只检测内存中已编码图片的文本框，不进行方向分类和文字识别
+	DetectBytes(ctx context.Context, data []byte, params DetectParams) (*DetectResultData, error)
+	// DetectImage 只检测已解码图片的文本框，不进行方向分类和文字识别
+	DetectImage(ctx context.Context, img image.Image, params DetectParams) (*DetectResultData, error)
+	// Close 释放引擎占用的资源，关闭后不可再使用
+	Close() error
+	// Info 返回引擎的描述信息
+	Info() EngineInfo
+}
+
+// EngineInfo 引擎描述信息
+type EngineInfo struct {
+	Name      string     `json:"name"`
+	ThreadNum int        `json:"thread_num,omitempty"`
+	PoolSize  int        `json:"pool_size,omitempty"`
+	Models    *ModelInfo `json:"models,omitempty"`
+	// Error 引擎不可用的原因
+	Error string `json:"error,omitempty"`
+}
+
+// unavailableEngine 引擎初始化失败时的占位实现，所有识别请求都返回初始化错误
+type unavailableEngine struct {
+	err error
+}
+
+// NewUnavailableEngine 创建一个始终返回ErrNotInitialized的引擎，用于初始化失败后继续提供服务
+//
+// 返回的错误同时包装了ErrNotInitialized和err。
+func NewUnavailableEngine(err error) Engine {
+	return &unavailableEngine{err: fmt.Errorf("%w: %w", ErrNotInitialized, err)}
+}
+
+func (e *unavailableEngine) RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error) {
+	return nil, e.err
+}
+
+func (e *unavailableEngine) RecognizeImage(ctx context.Context, img image.Image, params DetectParams) (*OCRResultData, error) {
+	return nil, e.err
+}
+
+func (e *unavailableEngine) RecognizeLines(ctx context.Context, lines []image.Image, params DetectParams) (*OCRResultData, error) {
+	return nil, e.err
+}
+
+func (e *unavailableEngine) DetectBytes(ctx context.Context, data []byte, params DetectParams) (*DetectResultData, error) {
+	return nil, e.err
+}
+
+func (e *unavailableEngine) DetectImage(ctx context.Context, img image.Image, params DetectParams) (*DetectResultData, error) {
+	return nil, e.err
+}
+
+func (e *unavailableEngine) Close() error {
+	return nil
+}
+
+func (e *unavailableEngine) Info() EngineInfo {
+	return EngineInfo{Name: "unavailable", Error: e.err.Error()}
+}
+
+// RecognizeFile 读取图片文件并使用engine识别
+func RecognizeFile(ctx context.Context, engine Engine, imagePath string, params DetectParams) (*OCRResultData, error) {
+	data, err := os.ReadFile(imagePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, imagePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取图片文件失败: %v", err)
+	}
+	return engine.RecognizeBytes(ctx, data, params)
+}
+
+type OCRBoxPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// OCRCharBox 单个字符的文本框，坐标为原图坐标
+type OCRCharBox struct {
+	BoxPoint []OCRBoxPoint `json:"box_point"`
+}
+
+// OCRCandidate 候选字符及其概率
+type OCRCandidate struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+type OCRTextBlock struct {
+	AngleIndex     int              `json:"angle_index"`
+	AngleScore     float64          `json:"angle_score"`
+	AngleTime      float64          `json:"angle_time"`
+	BlockTime      float64          `json:"block_time"`
+	BoxPoint       []OCRBoxPoint    `json:"box_point"`
+	BoxScore       float64          `json:"box_score"`
+	CharBoxes      []OCRCharBox     `json:"char_boxes,omitempty"`      // 与CharScores一一对应，请求char_boxes时返回
+	CharCandidates [][]OCRCandidate `json:"char_candidates,omitempty"` // 与CharScores一一对应，请求top_k时返回，按概率从高到低排列
+	CharScores     []float64        `json:"char_scores"`
+	CRNNTime       float64          `json:"crnn_time"`
+	Text           string           `json:"text"`
+	Vertical       bool             `json:"vertical"` // 竖排文本框，识别前已旋转或逐字拼接成横排
+}
+
+type OCRResultData struct {
+	SchemaVersion int            `json:"schema_version"`
+	DBNetTime     float64        `json:"db_net_time,omitempty"`
+	DetectTime    float64        `json:"detect_time,omitempty"`
+	PageRotation  int            `json:"page_rotation"` // 识别前将图片顺时针旋转的角度，请求auto_rotate时检测
+	SkewAngle     float64        `json:"skew_angle"`    // 文本行顺时针倾斜的角度，请求deskew时检测
+	TextBlocks    []OCRTextBlock `json:"text_blocks,omitempty"`
+	Texts         []string       `json:"texts"`
+	QRCode        bool           `json:"qr_code,omitempty"` // 是否存在二维码
+	// Image 标注了文本框的结果图片，json中为base64
+	Image       []byte `json:"image,omitempty"`
+	ImageFormat string `json:"image_format,omitempty"`
+	// Preprocess 实际执行的预处理步骤，未改变图片的步骤不在其中
+	Preprocess []string `json:"preprocess,omitempty"`
+	// ExifOrientation 原图的EXIF方向(1~8)，识别前已按该方向旋转，坐标为旋转后的图片坐标；没有EXIF方向时省略
+	ExifOrientation int `json:"exif_orientation,omitempty"`
+	// PageCount 多页图片的总页数，Pages 每一页的识别结果，此时Texts为所有页的文字，不返回TextBlocks和Image
+	PageCount int       `json:"page_count,omitempty"`
+	Pages     []OCRPage `json:"pages,omitempty"`
+}
+
+// OCRPage 多页图片中一页的识别结果，坐标为该页的图片坐标
+type OCRPage struct {
+	Page         int            `json:"page"` // 页码，从1开始
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	PageRotation int            `json:"page_rotation"`
+	SkewAngle    float64        `json:"skew_angle"`
+	TextBlocks   []OCRTextBlock `json:"text_blocks,omitempty"`
+	Texts        []string       `json:"texts"`
+	QRCode       bool           `json:"qr_code,omitempty"`
+	Image        []byte         `json:"image,omitempty"`
+	Preprocess   []string       `json:"preprocess,omitempty"`
+}
+
+// OCRTextBox 检测到的文本框，坐标为原图坐标
+type OCRTextBox struct {
+	BoxPoint []OCRBoxPoint `json:"box_point"`
+	BoxScore float64       `json:"box_score"`
+}
+
+// DetectResultData 只检测文本框的结果
+type DetectResultData struct {
+	SchemaVersion int          `json:"schema_version"`
+	DBNetTime     float64      `json:"db_net_time,omitempty"`
+	DetectTime    float64      `json:"detect_time,omitempty"`
+	Boxes         []OCRTextBox `json:"boxes"`
+	Preprocess    []string     `json:"preprocess,omitempty"` // 实际执行的预处理步骤
+	// ExifOrientation 原图的EXIF方向(1~8)，检测前已按该方向旋转；没有EXIF方向时省略
+	ExifOrientation int `json:"exif_orientation,omitempty"`
+}
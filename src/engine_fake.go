@@ -0,0 +1,234 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"sync"
+)
+
+// FakeEngine 模拟OCR引擎，不依赖C库和模型文件，用于测试以及接口联调
+type FakeEngine struct {
+	// Result 每次识别返回的结果，为nil时返回默认的模拟结果
+	Result *OCRResultData
+	// Err 不为nil时每次识别都返回该错误
+	Err error
+
+	mu         sync.Mutex
+	calls      int
+	lastParams DetectParams
+	closed     bool
+}
+
+// NewFakeEngine 创建返回默认模拟结果的引擎
+func NewFakeEngine() *FakeEngine {
+	return &FakeEngine{}
+}
+
+func (e *FakeEngine) RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error) {
+	return e.recognize(ctx, params)
+}
+
+func (e *FakeEngine) RecognizeImage(ctx context.Context, img image.Image, params DetectParams) (*OCRResultData, error) {
+	return e.recognize(ctx, params)
+}
+
+// RecognizeLines 每张图片返回一个模拟文本块，文本框为整张图片
+func (e *FakeEngine) RecognizeLines(ctx context.Context, lines []image.Image, params DetectParams) (*OCRResultData, error) {
+	result, err := e.recognize(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.TextBlocks) == 0 {
+		return result, nil
+	}
+	template := result.TextBlocks[0]
+	result.TextBlocks = make([]OCRTextBlock, 0, len(lines))
+	result.Texts = make([]string, 0, len(lines))
+	for _, line := range lines {
+		bounds := line.Bounds()
+		block := template
+		block.BoxPoint = []OCRBoxPoint{
+			{X: 0, Y: 0},
+			{X: bounds.Dx(), Y: 0},
+			{X: bounds.Dx(), Y: bounds.Dy()},
+			{X: 0, Y: bounds.Dy()},
+		}
+		block.BoxScore = 1
+		// 与C侧相同，高度不小于宽度1.5倍的图片按竖排文本识别
+		block.Vertical = float64(bounds.Dy()) >= float64(bounds.Dx())*1.5
+		result.TextBlocks = append(result.TextBlocks, block)
+		result.Texts = append(result.Texts, block.Text)
+	}
+	return result, nil
+}
+
+func (e *FakeEngine) DetectBytes(ctx context.Context, data []byte, params DetectParams) (*DetectResultData, error) {
+	return e.detect(ctx, params)
+}
+
+func (e *FakeEngine) DetectImage(ctx context.Context, img image.Image, params DetectParams) (*DetectResultData, error) {
+	return e.detect(ctx, params)
+}
+
+// detect 返回识别结果中文本块的文本框
+func (e *FakeEngine) detect(ctx context.Context, params DetectParams) (*DetectResultData, error) {
+	result, err := e.recognize(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	boxes := make([]OCRTextBox, 0, len(result.TextBlocks))
+	for _, block := range result.TextBlocks {
+		boxes = append(boxes, OCRTextBox{BoxPoint: block.BoxPoint, BoxScore: block.BoxScore})
+	}
+	return &DetectResultData{
+		SchemaVersion: result.SchemaVersion,
+		DBNetTime:     result.DBNetTime,
+		DetectTime:    result.DBNetTime,
+		Boxes:         boxes,
+	}, nil
+}
+
+func (e *FakeEngine) recognize(ctx context.Context, params DetectParams) (*OCRResultData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, ErrEngineClosed
+	}
+	e.calls++
+	e.lastParams = params
+	if e.Err != nil {
+		return nil, e.Err
+	}
+	result := fakeResult()
+	if e.Result != nil {
+		copied := *e.Result
+		result = &copied
+	}
+	if params.ResultImage != "" {
+		result.Image = fakeResultImage(params.ResultImage)
+	}
+	if params.CharBoxes {
+		result.TextBlocks = fakeCharBoxes(result.TextBlocks)
+	}
+	if params.TopK > 0 {
+		result.TextBlocks = fakeCharCandidates(result.TextBlocks, params.TopK)
+	}
+	return result, nil
+}
+
+func (e *FakeEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+	return nil
+}
+
+func (e *FakeEngine) Info() EngineInfo {
+	return EngineInfo{Name: "fake"}
+}
+
+// Calls 返回已执行的识别及检测次数
+func (e *FakeEngine) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+// LastParams 返回最近一次识别使用的检测参数
+func (e *FakeEngine) LastParams() DetectParams {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastParams
+}
+
+// fakeCharBoxes 将每个文本框按字符数等分作为字符的文本框
+func fakeCharBoxes(blocks []OCRTextBlock) []OCRTextBlock {
+	withBoxes := make([]OCRTextBlock, len(blocks))
+	for i, block := range blocks {
+		n := len(block.CharScores)
+		block.CharBoxes = make([]OCRCharBox, 0, n)
+		if len(block.BoxPoint) == 4 {
+			left, right := block.BoxPoint[0].X, block.BoxPoint[1].X
+			top, bottom := block.BoxPoint[0].Y, block.BoxPoint[2].Y
+			for c := 0; c < n; c++ {
+				x0 := left + (right-left)*c/n
+				x1 := left + (right-left)*(c+1)/n
+				block.CharBoxes = append(block.CharBoxes, OCRCharBox{BoxPoint: []OCRBoxPoint{
+					{X: x0, Y: top}, {X: x1, Y: top}, {X: x1, Y: bottom}, {X: x0, Y: bottom},
+				}})
+			}
+		}
+		withBoxes[i] = block
+	}
+	return withBoxes
+}
+
+// fakeCharCandidates 每个字符的第一个候选为识别出的字符，其余候选为数字并平分剩余概率
+func fakeCharCandidates(blocks []OCRTextBlock, topK int) []OCRTextBlock {
+	withCandidates := make([]OCRTextBlock, len(blocks))
+	for i, block := range blocks {
+		chars := []rune(block.Text)
+		block.CharCandidates = make([][]OCRCandidate, 0, len(block.CharScores))
+		for c, score := range block.CharScores {
+			candidates := make([]OCRCandidate, 0, topK)
+			if c < len(chars) {
+				candidates = append(candidates, OCRCandidate{Text: string(chars[c]), Score: score})
+			}
+			for k := len(candidates); k < topK; k++ {
+				candidates = append(candidates, OCRCandidate{Text: strconv.Itoa(k), Score: (1 - score) / float64(topK)})
+			}
+			block.CharCandidates = append(block.CharCandidates, candidates)
+		}
+		withCandidates[i] = block
+	}
+	return withCandidates
+}
+
+// fakeResultImage 返回按format编码的空白结果图片
+func fakeResultImage(format string) []byte {
+	img := image.NewGray(image.Rect(0, 0, 120, 40))
+	var buf bytes.Buffer
+	if format == kImageFormatJPEG {
+		jpeg.Encode(&buf, img, nil)
+	} else {
+		png.Encode(&buf, img)
+	}
+	return buf.Bytes()
+}
+
+// fakeResult 返回模拟的OCR结果
+func fakeResult() *OCRResultData {
+	return &OCRResultData{
+		SchemaVersion: ResultSchemaVersion,
+		DBNetTime:     0.1,
+		DetectTime:    0.5,
+		TextBlocks: []OCRTextBlock{
+			{
+				AngleIndex: 0,
+				AngleScore: 0.99,
+				AngleTime:  0.05,
+				BlockTime:  0.2,
+				BoxPoint: []OCRBoxPoint{
+					{X: 10, Y: 10},
+					{X: 100, Y: 10},
+					{X: 100, Y: 30},
+					{X: 10, Y: 30},
+				},
+				BoxScore:   0.92,
+				CharScores: []float64{0.9, 0.8, 0.95, 0.85},
+				CRNNTime:   0.1,
+				Text:       "模拟识别结果",
+			},
+		},
+		Texts:  []string{"模拟识别结果"},
+		QRCode: false,
+	}
+}
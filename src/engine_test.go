@@ -0,0 +1,49 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecognizeFile(t *testing.T) {
+	engine := NewFakeEngine()
+
+	t.Run("existing file", func(t *testing.T) {
+		path := t.TempDir() + "/image.png"
+		assert.NoError(t, os.WriteFile(path, []byte("png"), 0644))
+
+		result, err := RecognizeFile(context.Background(), engine, path, DefaultDetectParams())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"模拟识别结果"}, result.Texts)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := RecognizeFile(context.Background(), engine, "non-existent.png", DefaultDetectParams())
+		assert.ErrorIs(t, err, ErrFileNotFound)
+	})
+}
+
+func TestUnavailableEngine(t *testing.T) {
+	initErr := errors.New("模型文件不存在")
+	engine := NewUnavailableEngine(initErr)
+
+	_, err := engine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, initErr)
+	assert.ErrorIs(t, err, ErrNotInitialized)
+	_, err = engine.DetectBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrNotInitialized)
+	assert.Equal(t, "unavailable", engine.Info().Name)
+	assert.NoError(t, engine.Close())
+}
+
+func TestFakeEngineCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewFakeEngine().RecognizeBytes(ctx, []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,65 @@
+package src
+
+import "errors"
+
+// ErrorCode 原生层的错误码，与cpp/include/ocr.h中的OcrErrorCode保持一致
+type ErrorCode int
+
+const (
+	ErrCodeNone            ErrorCode = 0
+	ErrCodeNotInitialized  ErrorCode = 1
+	ErrCodeFileNotFound    ErrorCode = 2
+	ErrCodeDecodeImage     ErrorCode = 3
+	ErrCodeBufferTooSmall  ErrorCode = 4
+	ErrCodeInference       ErrorCode = 5
+	ErrCodeInvalidArgument ErrorCode = 6
+	ErrCodeImageTooLarge   ErrorCode = 7
+)
+
+// OcrError 带错误码的OCR错误
+//
+// 原生层返回的错误与下面的预定义错误按错误码匹配，
+// 调用方可以使用errors.Is(err, ErrImageDecode)等方式判断失败原因。
+type OcrError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *OcrError) Error() string {
+	return e.Message
+}
+
+// Is 错误码相同即视为同一种错误
+func (e *OcrError) Is(target error) bool {
+	t, ok := target.(*OcrError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	// ErrNotInitialized 引擎未初始化或初始化失败
+	ErrNotInitialized = &OcrError{Code: ErrCodeNotInitialized, Message: "OCR引擎未初始化"}
+	// ErrFileNotFound 图片或模型文件不存在
+	ErrFileNotFound = &OcrError{Code: ErrCodeFileNotFound, Message: "文件不存在"}
+	// ErrImageDecode 图片无法解码
+	ErrImageDecode = &OcrError{Code: ErrCodeDecodeImage, Message: "图片解码失败"}
+	// ErrBufferTooSmall 输出缓冲区不足
+	ErrBufferTooSmall = &OcrError{Code: ErrCodeBufferTooSmall, Message: "输出缓冲区不足"}
+	// ErrInference 模型加载或推理过程中发生异常
+	ErrInference = &OcrError{Code: ErrCodeInference, Message: "模型推理异常"}
+	// ErrInvalidArgument 参数无效
+	ErrInvalidArgument = &OcrError{Code: ErrCodeInvalidArgument, Message: "参数无效"}
+	// ErrImageTooLarge 图片文件或尺寸超过限制
+	ErrImageTooLarge = &OcrError{Code: ErrCodeImageTooLarge, Message: "图片过大"}
+)
+
+// 接口响应中OCR错误的code为kResponseCodeOcrError加上错误码，其他错误为500
+const kResponseCodeOcrError = 5000
+
+// ResponseCode 返回err在接口响应中对应的code
+func ResponseCode(err error) int {
+	var ocrErr *OcrError
+	if errors.As(err, &ocrErr) && ocrErr.Code != ErrCodeNone {
+		return kResponseCodeOcrError + int(ocrErr.Code)
+	}
+	return 500
+}
@@ -0,0 +1,31 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOcrErrorIs(t *testing.T) {
+	// 原生层返回的错误描述可能与预定义错误不同，按错误码匹配
+	native := &OcrError{Code: ErrCodeDecodeImage, Message: "image decode failed"}
+	assert.ErrorIs(t, native, ErrImageDecode)
+	assert.NotErrorIs(t, native, ErrInference)
+
+	wrapped := fmt.Errorf("识别失败: %w", native)
+	assert.ErrorIs(t, wrapped, ErrImageDecode)
+	assert.NotErrorIs(t, errors.New("图片解码失败"), ErrImageDecode)
+}
+
+func TestResponseCode(t *testing.T) {
+	assert.Equal(t, 5001, ResponseCode(ErrNotInitialized))
+	assert.Equal(t, 5002, ResponseCode(ErrFileNotFound))
+	assert.Equal(t, 5003, ResponseCode(fmt.Errorf("%w: 未知格式", ErrImageDecode)))
+	assert.Equal(t, 5004, ResponseCode(ErrBufferTooSmall))
+	assert.Equal(t, 5005, ResponseCode(ErrInference))
+	assert.Equal(t, 5006, ResponseCode(ErrInvalidArgument))
+	assert.Equal(t, 5007, ResponseCode(ErrImageTooLarge))
+	assert.Equal(t, 500, ResponseCode(errors.New("其他错误")))
+}
@@ -0,0 +1,110 @@
+package src
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// kExifOrientationNormal EXIF方向中的正常方向，2~8为拍摄时相机的旋转或镜像，需要变换后才是看到的方向
+const kExifOrientationNormal = 1
+
+// exifOrientation 读取JPEG中EXIF的Orientation标签，没有时返回0
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 0
+		}
+		marker := data[i+1]
+		// SOS之后是图像数据，EXIF只会出现在之前
+		if marker == 0xDA || marker == 0xD9 {
+			return 0
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 0
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(segment) >= 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 0
+}
+
+// tiffOrientation 在EXIF的TIFF结构的第一个IFD中查找Orientation标签(0x0112)
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for e := 0; e < count; e++ {
+		entry := offset + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// orient 按EXIF方向值(2~8)变换图片
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 水平翻转
+				sx, sy = w-1-dx, dy
+			case 3: // 旋转180度
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // 垂直翻转
+				sx, sy = dx, h-1-dy
+			case 5: // 沿主对角线翻转
+				sx, sy = dy, dx
+			case 6: // 顺时针旋转90度
+				sx, sy = dy, h-1-dx
+			case 7: // 沿副对角线翻转
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // 逆时针旋转90度
+				sx, sy = w-1-dy, dx
+			default:
+				sx, sy = dx, dy
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(sx, sy):])
+		}
+	}
+	return dst
+}
+
+// applyOrientation 按EXIF方向变换图片，方向为0、1或无效时原样返回
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= kExifOrientationNormal || orientation > 8 {
+		return img
+	}
+	return orient(toNRGBA(img), orientation)
+}
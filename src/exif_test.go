@@ -0,0 +1,73 @@
+package src
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegWithOrientation 编码一张JPEG并在SOI之后插入只包含Orientation标签的EXIF段
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	t.Run("exif orientation", func(t *testing.T) {
+		data := jpegWithOrientation(t, image.NewGray(image.Rect(0, 0, 40, 20)), 6)
+		assert.Equal(t, 6, exifOrientation(data))
+
+		img, orientation, err := decodeOrientedImage(data)
+		require.NoError(t, err)
+		assert.Equal(t, 6, orientation)
+		assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+
+		// 没有EXIF的图片不处理
+		img, orientation, err = decodeOrientedImage(encodePNG(t, image.NewGray(image.Rect(0, 0, 4, 4))))
+		require.NoError(t, err)
+		assert.Equal(t, 0, orientation)
+		assert.Equal(t, image.Rect(0, 0, 4, 4), img.Bounds())
+
+		// 截断的EXIF段不影响解码
+		assert.Equal(t, 0, exifOrientation(data[:30]))
+	})
+
+	t.Run("orient", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		src.SetNRGBA(0, 0, color.NRGBA{R: 1, A: 255})
+		src.SetNRGBA(1, 0, color.NRGBA{R: 2, A: 255})
+
+		clockwise := orient(src, 6)
+		assert.Equal(t, image.Rect(0, 0, 1, 2), clockwise.Rect)
+		assert.Equal(t, uint8(1), clockwise.NRGBAAt(0, 0).R)
+		assert.Equal(t, uint8(2), clockwise.NRGBAAt(0, 1).R)
+
+		counterClockwise := orient(src, 8)
+		assert.Equal(t, uint8(2), counterClockwise.NRGBAAt(0, 0).R)
+		assert.Equal(t, uint8(1), counterClockwise.NRGBAAt(0, 1).R)
+
+		flipped := orient(src, 2)
+		assert.Equal(t, uint8(2), flipped.NRGBAAt(0, 0).R)
+	})
+}
@@ -0,0 +1,122 @@
+package src
+
+import (
+	"bytes"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// inputFormat 支持的输入图片格式，上传、base64和image_url共用
+type inputFormat struct {
+	name       string
+	extensions []string
+	match      func(data []byte) bool
+	// engineNative 引擎可以直接解码已编码的数据，否则在Go侧解码后以像素传给引擎
+	engineNative bool
+}
+
+// inputFormats 按文件头识别的图片格式，GIF只识别第一帧
+var inputFormats = []inputFormat{
+	{
+		name:       "png",
+		extensions: []string{".png"},
+		match: func(data []byte) bool {
+			return bytes.HasPrefix(data, []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A})
+		},
+		engineNative: true,
+	},
+	{
+		name:       "jpg",
+		extensions: []string{".jpg", ".jpeg"},
+		match: func(data []byte) bool {
+			return bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF})
+		},
+		engineNative: true,
+	},
+	{
+		name:       "webp",
+		extensions: []string{".webp"},
+		match: func(data []byte) bool {
+			return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+		},
+	},
+	{
+		name:       "bmp",
+		extensions: []string{".bmp"},
+		match: func(data []byte) bool {
+			return bytes.HasPrefix(data, []byte("BM"))
+		},
+	},
+	{
+		name:       "gif",
+		extensions: []string{".gif"},
+		match: func(data []byte) bool {
+			return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+		},
+	},
+	{
+		name:       "tiff",
+		extensions: []string{".tif", ".tiff"},
+		match: func(data []byte) bool {
+			return bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))
+		},
+	},
+}
+
+// findInputFormat 按文件头查找图片格式，不支持时返回nil
+func findInputFormat(data []byte) *inputFormat {
+	if len(data) < 8 {
+		return nil
+	}
+	for i := range inputFormats {
+		if inputFormats[i].match(data) {
+			return &inputFormats[i]
+		}
+	}
+	return nil
+}
+
+// detectImageType 检测图片类型，返回格式名称，不支持时返回空字符串
+func detectImageType(data []byte) string {
+	if format := findInputFormat(data); format != nil {
+		return format.name
+	}
+	return ""
+}
+
+// isEngineNative 引擎是否可以直接解码该图片
+func isEngineNative(data []byte) bool {
+	format := findInputFormat(data)
+	return format != nil && format.engineNative
+}
+
+// isValidImageFile 按扩展名验证上传的文件类型
+func isValidImageFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, format := range inputFormats {
+		for _, e := range format.extensions {
+			if ext == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SupportedImageExtensions 返回支持上传的文件扩展名，不含"."
+func SupportedImageExtensions() []string {
+	var extensions []string
+	for _, format := range inputFormats {
+		for _, e := range format.extensions {
+			extensions = append(extensions, strings.TrimPrefix(e, "."))
+		}
+	}
+	return extensions
+}
@@ -0,0 +1,120 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+const (
+	// kMaxImagePixels 在Go侧解码的单张图片或单页的最大像素数，在分配像素之前按文件头中的尺寸检查
+	kMaxImagePixels = 50000000
+	// kMaxDecodePixels 单次请求解码多帧GIF时需要合成的所有帧的最大像素数
+	kMaxDecodePixels = 10 * kMaxImagePixels
+)
+
+// checkImageSize 检查图片尺寸是否超过kMaxImagePixels
+func checkImageSize(width, height int) error {
+	if int64(width)*int64(height) > kMaxImagePixels {
+		return fmt.Errorf("%w: 图片尺寸%dx%d超过%d像素", ErrImageTooLarge, width, height, kMaxImagePixels)
+	}
+	return nil
+}
+
+// decodeImage 解码inputFormats中支持的已编码图片，失败时返回ErrImageDecode，尺寸过大时返回ErrImageTooLarge
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImageDecode, err)
+	}
+	if err := checkImageSize(config.Width, config.Height); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImageDecode, err)
+	}
+	return img, nil
+}
+
+// decodeOrientedImage 解码图片并按JPEG的EXIF方向旋转或翻转，返回原图的EXIF方向，没有时为0
+//
+// OCR、二维码等所有在Go侧解码的路径都应使用该函数，保证看到的图片方向一致。
+func decodeOrientedImage(data []byte) (image.Image, int, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	orientation := exifOrientation(data)
+	return applyOrientation(img, orientation), orientation, nil
+}
+
+// imageToRGB 将图片转换为按行紧密排列的RGB像素，透明像素按白色背景合成
+func imageToRGB(img image.Image) (pix []byte, width, height int) {
+	bounds := img.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+	pix = make([]byte, width*height*3)
+
+	i := 0
+	switch src := img.(type) {
+	case *image.RGBA:
+		// RGBA为预乘alpha，合成到白色背景: c + 255 - a
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < width; x++ {
+				a := row[x*4+3]
+				pix[i] = row[x*4] + 255 - a
+				pix[i+1] = row[x*4+1] + 255 - a
+				pix[i+2] = row[x*4+2] + 255 - a
+				i += 3
+			}
+		}
+	case *image.NRGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < width; x++ {
+				a := uint32(row[x*4+3])
+				pix[i] = blendWhite(uint32(row[x*4]), a)
+				pix[i+1] = blendWhite(uint32(row[x*4+1]), a)
+				pix[i+2] = blendWhite(uint32(row[x*4+2]), a)
+				i += 3
+			}
+		}
+	case *image.YCbCr:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				yi := src.YOffset(x, y)
+				ci := src.COffset(x, y)
+				pix[i], pix[i+1], pix[i+2] = color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+				i += 3
+			}
+		}
+	case *image.Gray:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, y):]
+			for x := 0; x < width; x++ {
+				pix[i], pix[i+1], pix[i+2] = row[x], row[x], row[x]
+				i += 3
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				a := uint32(c.A)
+				pix[i] = blendWhite(uint32(c.R), a)
+				pix[i+1] = blendWhite(uint32(c.G), a)
+				pix[i+2] = blendWhite(uint32(c.B), a)
+				i += 3
+			}
+		}
+	}
+
+	return pix, width, height
+}
+
+// blendWhite 将非预乘的颜色分量按alpha合成到白色背景
+func blendWhite(c, a uint32) byte {
+	return byte((c*a + 255*(255-a)) / 255)
+}
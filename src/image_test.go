@@ -0,0 +1,64 @@
+package src
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageToRGB(t *testing.T) {
+	t.Run("nrgba with transparency", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		img.SetNRGBA(0, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+		img.SetNRGBA(1, 0, color.NRGBA{R: 0, G: 0, B: 0, A: 0})
+
+		pix, width, height := imageToRGB(img)
+		assert.Equal(t, 2, width)
+		assert.Equal(t, 1, height)
+		// 完全透明的像素合成为白色
+		assert.Equal(t, []byte{10, 20, 30, 255, 255, 255}, pix)
+	})
+
+	t.Run("rgba premultiplied", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		img.SetRGBA(0, 0, color.RGBA{R: 0, G: 0, B: 0, A: 0})
+
+		pix, _, _ := imageToRGB(img)
+		assert.Equal(t, []byte{255, 255, 255}, pix)
+	})
+
+	t.Run("sub image", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 4, 4))
+		img.SetGray(2, 2, color.Gray{Y: 7})
+		sub := img.SubImage(image.Rect(2, 2, 3, 3))
+
+		pix, width, height := imageToRGB(sub)
+		assert.Equal(t, 1, width)
+		assert.Equal(t, 1, height)
+		assert.Equal(t, []byte{7, 7, 7}, pix)
+	})
+
+	t.Run("ycbcr", func(t *testing.T) {
+		img := image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)
+		for i := range img.Y {
+			img.Y[i] = 255
+		}
+		for i := range img.Cb {
+			img.Cb[i] = 128
+			img.Cr[i] = 128
+		}
+
+		pix, _, _ := imageToRGB(img)
+		assert.Len(t, pix, 12)
+		assert.Equal(t, byte(255), pix[0])
+	})
+
+	t.Run("generic palette", func(t *testing.T) {
+		img := image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.RGBA{R: 1, G: 2, B: 3, A: 255}})
+
+		pix, _, _ := imageToRGB(img)
+		assert.Equal(t, []byte{1, 2, 3}, pix)
+	})
+}
@@ -0,0 +1,197 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 没有模型清单文件时使用的默认模型路径
+const kModelDbNet = "./models/dbnet.onnx"
+const kModelAngle = "./models/angle_net.onnx"
+const kModelCRNN = "./models/crnn_lite_lstm.onnx"
+const kModelKeys = "./models/keys.txt"
+
+// kDefaultModelManifest 默认的模型清单路径，该文件不存在时使用内置的默认模型路径
+const kDefaultModelManifest = "./models/manifest.json"
+
+// ModelFile 模型清单中的单个文件
+type ModelFile struct {
+	// Path 文件路径，相对路径相对于清单文件所在目录
+	Path string `json:"path"`
+	// SHA256 文件的SHA-256，为空时不校验
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// ModelManifest 模型清单，描述一套可以一起使用的模型文件
+type ModelManifest struct {
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+	DbNet   ModelFile `json:"dbnet"`
+	Angle   ModelFile `json:"angle"`
+	CRNN    ModelFile `json:"crnn"`
+	Keys    ModelFile `json:"keys"`
+	// CharsetSize keys文件中的字符数，需要与CRNN模型的输出一致，为0时不校验
+	CharsetSize int `json:"charset_size,omitempty"`
+
+	// source 清单文件路径，内置默认清单为空
+	source string
+}
+
+// ModelInfo 已加载模型的描述信息
+type ModelInfo struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Manifest    string `json:"manifest,omitempty"`
+	CharsetSize int    `json:"charset_size,omitempty"`
+}
+
+// DefaultModelManifest 返回使用默认模型路径的清单，不校验文件内容
+func DefaultModelManifest() *ModelManifest {
+	return &ModelManifest{
+		Name:  "default",
+		DbNet: ModelFile{Path: kModelDbNet},
+		Angle: ModelFile{Path: kModelAngle},
+		CRNN:  ModelFile{Path: kModelCRNN},
+		Keys:  ModelFile{Path: kModelKeys},
+	}
+}
+
+// LoadModelManifest 读取模型清单文件，并将相对路径转换为相对于清单所在目录的路径
+//
+// path为默认清单路径且文件不存在时返回DefaultModelManifest。
+func LoadModelManifest(path string) (*ModelManifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && path == kDefaultModelManifest {
+		return DefaultModelManifest(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取模型清单失败: %w", err)
+	}
+
+	manifest := &ModelManifest{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(manifest); err != nil {
+		return nil, fmt.Errorf("解析模型清单%s失败: %w", path, err)
+	}
+	manifest.source = path
+
+	dir := filepath.Dir(path)
+	for _, file := range manifest.files() {
+		if file.Path != "" && !filepath.IsAbs(file.Path) {
+			file.Path = filepath.Join(dir, file.Path)
+		}
+	}
+	return manifest, nil
+}
+
+// files 返回清单中的所有文件及其名称
+func (m *ModelManifest) files() map[string]*ModelFile {
+	return map[string]*ModelFile{
+		"dbnet": &m.DbNet,
+		"angle": &m.Angle,
+		"crnn":  &m.CRNN,
+		"keys":  &m.Keys,
+	}
+}
+
+// Validate 检查模型文件是否存在、SHA-256以及字符集大小是否与清单一致，返回所有发现的问题
+func (m *ModelManifest) Validate() error {
+	var errs []error
+	for _, name := range []string{"dbnet", "angle", "crnn", "keys"} {
+		if err := validateModelFile(name, *m.files()[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if m.CharsetSize > 0 && m.Keys.Path != "" {
+		size, err := countLines(m.Keys.Path)
+		if err == nil && size != m.CharsetSize {
+			errs = append(errs, fmt.Errorf("keys字符集大小为%d，清单要求%d: %s", size, m.CharsetSize, m.Keys.Path))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("模型集%s校验失败: %w", m.Name, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Info 返回清单的描述信息
+func (m *ModelManifest) Info() ModelInfo {
+	return ModelInfo{
+		Name:        m.Name,
+		Version:     m.Version,
+		Manifest:    m.source,
+		CharsetSize: m.CharsetSize,
+	}
+}
+
+// validateModelFile 检查单个模型文件
+func validateModelFile(name string, file ModelFile) error {
+	if file.Path == "" {
+		return fmt.Errorf("%s模型未配置路径", name)
+	}
+	stat, err := os.Stat(file.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s模型文件不存在: %s", name, file.Path)
+		}
+		return fmt.Errorf("%s模型文件无法访问: %v", name, err)
+	}
+	if stat.IsDir() {
+		return fmt.Errorf("%s模型路径是目录: %s", name, file.Path)
+	}
+	if file.SHA256 == "" {
+		return nil
+	}
+
+	sum, err := fileSHA256(file.Path)
+	if err != nil {
+		return fmt.Errorf("%s模型文件读取失败: %v", name, err)
+	}
+	if !strings.EqualFold(sum, file.SHA256) {
+		return fmt.Errorf("%s模型文件SHA-256不一致: %s，实际为%s，清单要求%s", name, file.Path, sum, file.SHA256)
+	}
+	return nil
+}
+
+// fileSHA256 计算文件的SHA-256
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// countLines 统计文件行数，与CrnnNet读取keys文件的方式一致
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		count++
+	}
+	return count, scanner.Err()
+}
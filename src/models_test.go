@@ -0,0 +1,120 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModelSet 在临时目录中写入一套模型文件和清单
+func writeModelSet(t *testing.T, manifest string) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dbnet.onnx": "dbnet",
+		"angle.onnx": "angle",
+		"crnn.onnx":  "crnn",
+		"keys.txt":   "a\nb\nc\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	path := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(path, []byte(manifest), 0644))
+	return path
+}
+
+func TestLoadModelManifest(t *testing.T) {
+	t.Run("relative paths", func(t *testing.T) {
+		path := writeModelSet(t, `{
+			"name": "test", "version": "1",
+			"dbnet": {"path": "dbnet.onnx"},
+			"angle": {"path": "angle.onnx"},
+			"crnn": {"path": "crnn.onnx"},
+			"keys": {"path": "keys.txt"},
+			"charset_size": 3
+		}`)
+
+		manifest, err := LoadModelManifest(path)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(filepath.Dir(path), "dbnet.onnx"), manifest.DbNet.Path)
+		assert.Equal(t, ModelInfo{Name: "test", Version: "1", Manifest: path, CharsetSize: 3}, manifest.Info())
+	})
+
+	t.Run("missing default manifest", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(t.TempDir()))
+		defer os.Chdir(wd)
+
+		manifest, err := LoadModelManifest(kDefaultModelManifest)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultModelManifest(), manifest)
+	})
+
+	t.Run("missing explicit manifest", func(t *testing.T) {
+		_, err := LoadModelManifest(filepath.Join(t.TempDir(), "manifest.json"))
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		path := writeModelSet(t, `{"name": "test", "detector": {"path": "dbnet.onnx"}}`)
+		_, err := LoadModelManifest(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestModelManifestValidate(t *testing.T) {
+	const valid = `{
+		"name": "test",
+		"dbnet": {"path": "dbnet.onnx", "sha256": "%s"},
+		"angle": {"path": "angle.onnx"},
+		"crnn": {"path": "%s"},
+		"keys": {"path": "keys.txt"},
+		"charset_size": %d
+	}`
+	dbnetSum := "a8b9b2b4ba5e5ee2cd6a3ebfb1dc0a4e5ea4a71fe0a2d1c79e6d9a7f8b3e3e06"
+
+	load := func(t *testing.T, manifest string) *ModelManifest {
+		loaded, err := LoadModelManifest(writeModelSet(t, manifest))
+		require.NoError(t, err)
+		return loaded
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		manifest := load(t, fmt.Sprintf(valid, "", "crnn.onnx", 3))
+		sum, err := fileSHA256(manifest.DbNet.Path)
+		require.NoError(t, err)
+
+		manifest = load(t, fmt.Sprintf(valid, sum, "crnn.onnx", 3))
+		assert.NoError(t, manifest.Validate())
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		manifest := load(t, fmt.Sprintf(valid, dbnetSum, "missing.onnx", 5531))
+		err := manifest.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "dbnet模型文件SHA-256不一致")
+		assert.Contains(t, err.Error(), "crnn模型文件不存在")
+		assert.Contains(t, err.Error(), "keys字符集大小为3，清单要求5531")
+	})
+
+	t.Run("repository manifest", func(t *testing.T) {
+		manifest, err := LoadModelManifest("../models/manifest.json")
+		require.NoError(t, err)
+		assert.Equal(t, 5531, manifest.CharsetSize)
+
+		// 仓库中不包含crnn模型，其余文件必须与清单一致
+		err = manifest.Validate()
+		if _, statErr := os.Stat(manifest.CRNN.Path); statErr == nil {
+			assert.NoError(t, err)
+		} else {
+			require.Error(t, err)
+			assert.NotContains(t, err.Error(), "SHA-256")
+			assert.NotContains(t, err.Error(), "字符集")
+		}
+	})
+}
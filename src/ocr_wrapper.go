@@ -16,87 +16,412 @@ package src
 */
 import "C"
 import (
-	"encoding/json"
+	"context"
+	"fmt"
+	"image"
 	"runtime"
+	"strings"
+	"sync"
 	"unsafe"
 )
 
-const kModelDbNet = "./models/dbnet.onnx"
-const kModelAngle = "./models/angle_net.onnx"
-const kModelCRNN = "./models/crnn_lite_lstm.onnx"
-const kModelKeys = "./models/keys.txt"
-
-const kDefaultBufferLen = 10 * 1024
-
-var (
-	buffer [kDefaultBufferLen]byte
-)
-
-type OCRBoxPoint struct {
-	X int `json:"x"`
-	Y int `json:"y"`
+// OcrLiteEngine 基于cgo调用OcrLiteOnnx的单个OCR实例
+//
+// 实例不能并发识别，并发由EnginePool通过多个实例提供。
+type OcrLiteEngine struct {
+	mu        sync.Mutex
+	handle    C.OcrHandle
+	threadNum int
+	models    ModelInfo
 }
 
-type OCRTextBlock struct {
-	AngleIndex int           `json:"angle_index"`
-	AngleScore float64       `json:"angle_score"`
-	AngleTime  float64       `json:"angle_time"`
-	BlockTime  float64       `json:"block_time"`
-	BoxPoint   []OCRBoxPoint `json:"box_point"`
-	BoxScore   float64       `json:"box_score"`
-	CharScores []float64     `json:"char_scores"`
-	CRNNTime   float64       `json:"crnn_time"`
-	Text       string        `json:"text"`
+// NewOcrLiteEngine 按配置创建由多个OcrLite实例组成的引擎池，每个实例加载models中的模型
+func NewOcrLiteEngine(config Config, models *ModelManifest) (Engine, error) {
+	return NewEnginePool(config.PoolSize, config.QueueSize, func(index int) (Engine, error) {
+		return newOcrLiteInstance(config.ThreadNum, models)
+	})
 }
 
-type OCRResultData struct {
-	DBNetTime  float64        `json:"db_net_time,omitempty"`
-	DetectTime float64        `json:"detect_time,omitempty"`
-	TextBlocks []OCRTextBlock `json:"text_blocks,omitempty"`
-	Texts      []string       `json:"texts"`
-	QRCode     bool           `json:"qr_code,omitempty"` // 是否存在二维码
-}
+// newOcrLiteInstance 创建一个独立加载模型的OcrLite实例
+func newOcrLiteInstance(threadNum int, models *ModelManifest) (*OcrLiteEngine, error) {
+	if err := checkSchemaVersion(int(C.ocr_schema_version())); err != nil {
+		return nil, fmt.Errorf("OcrLiteOnnx动态库与Go代码版本不一致: %w", err)
+	}
+	if err := checkParamsVersion(int(C.ocr_params_version())); err != nil {
+		return nil, fmt.Errorf("OcrLiteOnnx动态库与Go代码版本不一致: %w", err)
+	}
 
-func Init() int {
 	// dbNet, angle, crnn, keys string
-	threadNum := runtime.NumCPU()
-	cDbNet := C.CString(kModelDbNet) // to c char*
-	cAngle := C.CString(kModelAngle) // to c char*
-	cCRNN := C.CString(kModelCRNN)   // to c char*
-	cKeys := C.CString(kModelKeys)   // to c char*
+	cDbNet := C.CString(models.DbNet.Path) // to c char*
+	cAngle := C.CString(models.Angle.Path) // to c char*
+	cCRNN := C.CString(models.CRNN.Path)   // to c char*
+	cKeys := C.CString(models.Keys.Path)   // to c char*
 
-	ret := C.ocr_init(C.int(threadNum), cDbNet, cAngle, cCRNN, cKeys)
+	var code C.int
+	handle := C.ocr_create(C.int(threadNum), cDbNet, cAngle, cCRNN, cKeys, &code)
 
 	C.free(unsafe.Pointer(cDbNet))
 	C.free(unsafe.Pointer(cAngle))
 	C.free(unsafe.Pointer(cCRNN))
 	C.free(unsafe.Pointer(cKeys))
-	return int(ret)
+
+	if handle == nil {
+		return nil, fmt.Errorf("OCR引擎初始化失败，请检查模型文件: %w", nativeError(code))
+	}
+	return &OcrLiteEngine{handle: handle, threadNum: threadNum, models: models.Info()}, nil
 }
 
-func Detect(imagePath string) (bool, *OCRResultData) {
-	resultLen := C.int(kDefaultBufferLen)
+func (e *OcrLiteEngine) RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: 图片数据不能为空", ErrInvalidArgument)
+	}
+	if !isEngineNative(data) {
+		img, err := decodeImage(data)
+		if err != nil {
+			return nil, err
+		}
+		return e.RecognizeImage(ctx, img, params)
+	}
+	cParams, freeParams := toCDetectParams(params)
+	defer freeParams()
+	return e.detect(ctx, func(code *C.int) *C.OcrDetectResult {
+		return C.ocr_detect_buffer(e.handle, (*C.uchar)(unsafe.Pointer(&data[0])), C.int(len(data)), &cParams, code)
+	})
+}
 
-	// 构造C的缓冲区
-	cTempBuffer := (*C.char)(unsafe.Pointer(&buffer[0]))
-	cImagePath := C.CString(imagePath)
-	defer C.free(unsafe.Pointer(cImagePath))
+func (e *OcrLiteEngine) RecognizeImage(ctx context.Context, img image.Image, params DetectParams) (*OCRResultData, error) {
+	pix, width, height := imageToRGB(img)
+	if len(pix) == 0 {
+		return nil, fmt.Errorf("%w: 图片尺寸无效", ErrInvalidArgument)
+	}
+	cParams, freeParams := toCDetectParams(params)
+	defer freeParams()
+	return e.detect(ctx, func(code *C.int) *C.OcrDetectResult {
+		return C.ocr_detect_pixels(e.handle, (*C.uchar)(unsafe.Pointer(&pix[0])),
+			C.int(width), C.int(height), C.int(width*3), &cParams, code)
+	})
+}
 
-	isSuccess := C.ocr_detect2(cImagePath, cTempBuffer, &resultLen)
-	if int(isSuccess) != 1 {
-		return false, nil
+func (e *OcrLiteEngine) RecognizeLines(ctx context.Context, lines []image.Image, params DetectParams) (*OCRResultData, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: 文本行图片不能为空", ErrInvalidArgument)
 	}
-	result := C.GoStringN(cTempBuffer, resultLen)
-	var vo OCRResultData
 
-	err := json.Unmarshal([]byte(result), &vo)
-	if err != nil {
-		return false, nil
+	// C侧结构体不能持有Go内存，像素复制到C内存中
+	cImages := (*C.OcrImage)(C.calloc(C.size_t(len(lines)), C.size_t(unsafe.Sizeof(C.OcrImage{}))))
+	images := unsafe.Slice(cImages, len(lines))
+	defer func() {
+		for _, img := range images {
+			C.free(unsafe.Pointer(img.rgb))
+		}
+		C.free(unsafe.Pointer(cImages))
+	}()
+	for i, line := range lines {
+		pix, width, height := imageToRGB(line)
+		if len(pix) == 0 {
+			return nil, fmt.Errorf("%w: 第%d张文本行图片尺寸无效", ErrInvalidArgument, i+1)
+		}
+		images[i] = C.OcrImage{
+			rgb:    (*C.uchar)(C.CBytes(pix)),
+			width:  C.int(width),
+			height: C.int(height),
+			stride: C.int(width * 3),
+		}
 	}
 
-	return true, &vo
+	cParams, freeParams := toCDetectParams(params)
+	defer freeParams()
+	return e.detect(ctx, func(code *C.int) *C.OcrDetectResult {
+		return C.ocr_recognize_lines(e.handle, cImages, C.int(len(lines)), &cParams, code)
+	})
+}
+
+func (e *OcrLiteEngine) DetectBytes(ctx context.Context, data []byte, params DetectParams) (*DetectResultData, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: 图片数据不能为空", ErrInvalidArgument)
+	}
+	if !isEngineNative(data) {
+		img, err := decodeImage(data)
+		if err != nil {
+			return nil, err
+		}
+		return e.DetectImage(ctx, img, params)
+	}
+	cParams, freeParams := toCDetectParams(params)
+	defer freeParams()
+	return e.detectBoxes(ctx, func(code *C.int) *C.OcrBoxResult {
+		return C.ocr_detect_boxes_buffer(e.handle, (*C.uchar)(unsafe.Pointer(&data[0])), C.int(len(data)), &cParams, code)
+	})
+}
+
+func (e *OcrLiteEngine) DetectImage(ctx context.Context, img image.Image, params DetectParams) (*DetectResultData, error) {
+	pix, width, height := imageToRGB(img)
+	if len(pix) == 0 {
+		return nil, fmt.Errorf("%w: 图片尺寸无效", ErrInvalidArgument)
+	}
+	cParams, freeParams := toCDetectParams(params)
+	defer freeParams()
+	return e.detectBoxes(ctx, func(code *C.int) *C.OcrBoxResult {
+		return C.ocr_detect_boxes_pixels(e.handle, (*C.uchar)(unsafe.Pointer(&pix[0])),
+			C.int(width), C.int(height), C.int(width*3), &cParams, code)
+	})
+}
+
+// toCDetectParams 转换为C侧的检测参数，字符串复制到C内存中，调用结束后需要调用返回的free释放
+func toCDetectParams(params DetectParams) (C.OcrDetectParams, func()) {
+	cParams := C.OcrDetectParams{
+		padding:        C.int(params.Padding),
+		maxSideLen:     C.int(params.MaxSideLen),
+		boxScoreThresh: C.float(params.BoxScoreThresh),
+		boxThresh:      C.float(params.BoxThresh),
+		unClipRatio:    C.float(params.UnClipRatio),
+		doAngle:        cBool(params.DoAngle),
+		mostAngle:      cBool(params.MostAngle),
+		resultImage:    cImageFormat(params.ResultImage),
+		labelBoxes:     cBool(params.LabelBoxes),
+		charBoxes:      cBool(params.CharBoxes),
+		autoRotate:     cBool(params.AutoRotate),
+		tile:           cBool(params.Tile),
+		tileSize:       C.int(params.TileSize),
+		tileOverlap:    C.int(params.TileOverlap),
+		deskew:         cBool(params.Deskew),
+		deskewMapBack:  cBool(params.DeskewMapBack),
+		topK:           C.int(params.TopK),
+		beamWidth:      C.int(params.BeamWidth),
+		hotwordBoost:   C.float(params.HotwordBoost),
+	}
+
+	var allocated []unsafe.Pointer
+	cString := func(s string) *C.char {
+		if s == "" {
+			return nil
+		}
+		cs := C.CString(s)
+		allocated = append(allocated, unsafe.Pointer(cs))
+		return cs
+	}
+	if len(params.Hotwords) > 0 {
+		cHotwords := (**C.char)(C.calloc(C.size_t(len(params.Hotwords)), C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+		allocated = append(allocated, unsafe.Pointer(cHotwords))
+		for i, hotword := range params.Hotwords {
+			unsafe.Slice(cHotwords, len(params.Hotwords))[i] = cString(hotword)
+		}
+		cParams.hotwords = cHotwords
+		cParams.hotwordsLen = C.int(len(params.Hotwords))
+	}
+	cParams.charWhitelist = cString(params.CharWhitelist)
+	cParams.charBlacklist = cString(params.CharBlacklist)
+	cParams.charSets = cString(strings.Join(params.CharSets, ","))
+	return cParams, func() {
+		for _, p := range allocated {
+			C.free(p)
+		}
+	}
+}
+
+// cImageFormat 转换结果图片格式
+func cImageFormat(format string) C.int {
+	switch format {
+	case kImageFormatPNG:
+		return C.kOcrImagePng
+	case kImageFormatJPEG:
+		return C.kOcrImageJpeg
+	default:
+		return C.kOcrImageNone
+	}
+}
+
+func cBool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// run 在实例锁内执行fn，ctx已取消或实例已关闭时不执行
+func (e *OcrLiteEngine) run(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.handle == nil {
+		return ErrEngineClosed
+	}
+	return fn()
+}
+
+// detect 在实例锁内调用C识别函数，并将结果转换后释放
+func (e *OcrLiteEngine) detect(ctx context.Context, call func(code *C.int) *C.OcrDetectResult) (*OCRResultData, error) {
+	var result *OCRResultData
+	err := e.run(ctx, func() error {
+		var code C.int
+		cResult := call(&code)
+		if cResult == nil {
+			return nativeError(code)
+		}
+		defer C.ocr_free_result(cResult)
+
+		if err := checkSchemaVersion(int(cResult.schemaVersion)); err != nil {
+			return err
+		}
+		result = convertResult(cResult)
+		return nil
+	})
+	return result, err
+}
+
+// detectBoxes 在实例锁内调用C文本框检测函数，并将结果转换后释放
+func (e *OcrLiteEngine) detectBoxes(ctx context.Context, call func(code *C.int) *C.OcrBoxResult) (*DetectResultData, error) {
+	var result *DetectResultData
+	err := e.run(ctx, func() error {
+		var code C.int
+		cResult := call(&code)
+		if cResult == nil {
+			return nativeError(code)
+		}
+		defer C.ocr_free_box_result(cResult)
+
+		if err := checkSchemaVersion(int(cResult.schemaVersion)); err != nil {
+			return err
+		}
+		result = convertBoxResult(cResult)
+		return nil
+	})
+	return result, err
+}
+
+// detectFileJSON 用默认参数识别imagePath，返回C侧toJson的原始输出，用于生成结果结构的基准文件
+func (e *OcrLiteEngine) detectFileJSON(ctx context.Context, imagePath string) ([]byte, error) {
+	cPath := C.CString(imagePath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	params := DefaultDetectParams()
+	var data []byte
+	err := e.run(ctx, func() error {
+		// ocr_last_error读取的是线程局部的错误码，与ocr_detect_handle必须在同一个线程上调用
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		bufferLen := C.int(1 << 16)
+		for {
+			buffer := make([]byte, bufferLen)
+			size := bufferLen
+			ok := C.ocr_detect_handle(e.handle, cPath, (*C.char)(unsafe.Pointer(&buffer[0])), &size,
+				C.int(params.Padding), C.int(params.MaxSideLen), C.float(params.BoxScoreThresh), C.float(params.BoxThresh),
+				C.float(params.UnClipRatio), C.bool(cBool(params.DoAngle)), C.bool(cBool(params.MostAngle)))
+			if ok == C.kOcrSuccess {
+				data = buffer[:size]
+				return nil
+			}
+			code := C.ocr_last_error()
+			if ErrorCode(code) != ErrCodeBufferTooSmall || size <= bufferLen {
+				return nativeError(code)
+			}
+			// 缓冲区不足时size为需要的长度
+			bufferLen = size
+		}
+	})
+	return data, err
+}
+
+// nativeError 将C侧的错误码转换为OcrError
+func nativeError(code C.int) error {
+	return &OcrError{Code: ErrorCode(code), Message: C.GoString(C.ocr_error_message(code))}
+}
+
+// convertResult 遍历C侧的结果结构体，转换为Go的识别结果
+func convertResult(cResult *C.OcrDetectResult) *OCRResultData {
+	cBlocks := unsafe.Slice(cResult.textBlocks, int(cResult.textBlocksLen))
+	result := &OCRResultData{
+		SchemaVersion: int(cResult.schemaVersion),
+		DBNetTime:     float64(cResult.dbNetTime),
+		DetectTime:    float64(cResult.detectTime),
+		PageRotation:  int(cResult.pageRotation),
+		SkewAngle:     float64(cResult.skewAngle),
+		TextBlocks:    make([]OCRTextBlock, 0, len(cBlocks)),
+		Texts:         make([]string, 0, len(cBlocks)),
+	}
+	if cResult.image != nil {
+		result.Image = C.GoBytes(unsafe.Pointer(cResult.image), cResult.imageLen)
+	}
+
+	for _, cBlock := range cBlocks {
+		block := OCRTextBlock{
+			AngleIndex: int(cBlock.angleIndex),
+			AngleScore: float64(cBlock.angleScore),
+			AngleTime:  float64(cBlock.angleTime),
+			BlockTime:  float64(cBlock.blockTime),
+			BoxPoint:   make([]OCRBoxPoint, len(cBlock.boxPoint)),
+			BoxScore:   float64(cBlock.boxScore),
+			CRNNTime:   float64(cBlock.crnnTime),
+			Text:       C.GoString(cBlock.text),
+			Vertical:   cBlock.vertical != 0,
+		}
+		for i, point := range cBlock.boxPoint {
+			block.BoxPoint[i] = OCRBoxPoint{X: int(point.x), Y: int(point.y)}
+		}
+		cScores := unsafe.Slice(cBlock.charScores, int(cBlock.charScoresLen))
+		block.CharScores = make([]float64, len(cScores))
+		for i, score := range cScores {
+			block.CharScores[i] = float64(score)
+		}
+		for _, cCharBox := range unsafe.Slice(cBlock.charBoxes, int(cBlock.charBoxesLen)) {
+			charBox := OCRCharBox{BoxPoint: make([]OCRBoxPoint, len(cCharBox.boxPoint))}
+			for i, point := range cCharBox.boxPoint {
+				charBox.BoxPoint[i] = OCRBoxPoint{X: int(point.x), Y: int(point.y)}
+			}
+			block.CharBoxes = append(block.CharBoxes, charBox)
+		}
+		for _, cCharCandidates := range unsafe.Slice(cBlock.charCandidates, int(cBlock.charCandidatesLen)) {
+			cCandidates := unsafe.Slice(cCharCandidates.candidates, int(cCharCandidates.candidatesLen))
+			candidates := make([]OCRCandidate, len(cCandidates))
+			for i, cCandidate := range cCandidates {
+				candidates[i] = OCRCandidate{Text: C.GoString(cCandidate.text), Score: float64(cCandidate.score)}
+			}
+			block.CharCandidates = append(block.CharCandidates, candidates)
+		}
+
+		result.TextBlocks = append(result.TextBlocks, block)
+		result.Texts = append(result.Texts, block.Text)
+	}
+
+	return result
+}
+
+// convertBoxResult 转换C侧的文本框检测结果
+func convertBoxResult(cResult *C.OcrBoxResult) *DetectResultData {
+	cBoxes := unsafe.Slice(cResult.boxes, int(cResult.boxesLen))
+	result := &DetectResultData{
+		SchemaVersion: int(cResult.schemaVersion),
+		DBNetTime:     float64(cResult.dbNetTime),
+		DetectTime:    float64(cResult.detectTime),
+		Boxes:         make([]OCRTextBox, 0, len(cBoxes)),
+	}
+	for _, cBox := range cBoxes {
+		box := OCRTextBox{
+			BoxPoint: make([]OCRBoxPoint, len(cBox.boxPoint)),
+			BoxScore: float64(cBox.boxScore),
+		}
+		for i, point := range cBox.boxPoint {
+			box.BoxPoint[i] = OCRBoxPoint{X: int(point.x), Y: int(point.y)}
+		}
+		result.Boxes = append(result.Boxes, box)
+	}
+	return result
+}
+
+func (e *OcrLiteEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.handle != nil {
+		C.ocr_destroy(e.handle)
+		e.handle = nil
+	}
+	return nil
 }
 
-func CleanUp() {
-	C.ocr_cleanup()
+func (e *OcrLiteEngine) Info() EngineInfo {
+	models := e.models
+	return EngineInfo{Name: "ocrlite", ThreadNum: e.threadNum, Models: &models}
 }
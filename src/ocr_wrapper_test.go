@@ -115,7 +115,6 @@ func TestOCRConstants(t *testing.T) {
 	assert.Equal(t, "./models/angle_net.onnx", kModelAngle)
 	assert.Equal(t, "./models/crnn_lite_lstm.onnx", kModelCRNN)
 	assert.Equal(t, "./models/keys.txt", kModelKeys)
-	assert.Equal(t, 10*1024, kDefaultBufferLen)
 }
 
 func TestJSONMarshalUnmarshal(t *testing.T) {
@@ -0,0 +1,343 @@
+package src
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 与cpp/include/ocr.h中的默认值保持一致
+const (
+	kDefaultPadding        = 50
+	kDefaultMaxSideLen     = 1024
+	kDefaultBoxScoreThresh = 0.6
+	kDefaultBoxThresh      = 0.3
+	kDefaultUnClipRatio    = 2.0
+	kDefaultHotwordBoost   = 1.5
+	kDefaultTileSize       = 1024
+	kDefaultTileOverlap    = 128
+)
+
+// 结果图片格式
+const (
+	kImageFormatPNG  = "png"
+	kImageFormatJPEG = "jpeg"
+)
+
+// 识别参数的上限
+const (
+	// kMaxTopK 每个字符最多返回的候选字符数量
+	kMaxTopK = 10
+	// kMaxBeamWidth beam search的最大beam宽度
+	kMaxBeamWidth = 32
+	// kMaxHotwords 最多热词数量，包括模型配置和请求中的热词
+	kMaxHotwords = 1000
+	// kMaxHotwordLen 单个热词的最大字符数
+	kMaxHotwordLen = 32
+	// kMaxCharListLen 字符白名单、黑名单的最大字符数
+	kMaxCharListLen = 10000
+	// kMinTileSize、kMaxTileSize 分块边长的取值范围
+	kMinTileSize = 256
+	kMaxTileSize = 4096
+)
+
+// charSets 命名字符集，与cpp/src/CrnnNet.cpp中的inCharSet保持一致
+var charSets = []string{"digits", "upper", "lower", "alpha", "alnum", "hanzi", "punct"}
+
+func isCharSet(name string) bool {
+	for _, charSet := range charSets {
+		if charSet == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectParams 检测参数
+type DetectParams struct {
+	// Padding 图片四周补白的像素数
+	Padding int `json:"padding"`
+	// MaxSideLen 缩放后图片长边的最大长度，0表示不缩放
+	MaxSideLen int `json:"max_side_len"`
+	// BoxScoreThresh 文本框置信度阈值，低于该值的文本框被丢弃
+	BoxScoreThresh float64 `json:"box_score_thresh"`
+	// BoxThresh 文本区域二值化阈值
+	BoxThresh float64 `json:"box_thresh"`
+	// UnClipRatio 文本框扩张比例，值越大文本框越大
+	UnClipRatio float64 `json:"un_clip_ratio"`
+	// DoAngle 是否进行文字方向检测
+	DoAngle bool `json:"do_angle"`
+	// MostAngle 是否按多数文本块的方向统一所有文本块的方向
+	MostAngle bool `json:"most_angle"`
+	// ResultImage 返回标注了文本框的结果图片的格式，png或jpeg，为空时不返回
+	ResultImage string `json:"result_image,omitempty"`
+	// LabelBoxes 在结果图片上标注文本框序号和置信度
+	LabelBoxes bool `json:"label_boxes,omitempty"`
+	// CharBoxes 返回每个字符的文本框
+	CharBoxes bool `json:"char_boxes,omitempty"`
+	// TopK 每个字符返回概率最高的候选字符数量，0表示不返回
+	TopK int `json:"top_k,omitempty"`
+	// BeamWidth CTC beam search的beam宽度，0或1表示使用贪心解码
+	BeamWidth int `json:"beam_width,omitempty"`
+	// Hotwords 热词，beam search时提高包含热词的结果的得分，贪心解码时不生效
+	Hotwords []string `json:"hotwords,omitempty"`
+	// HotwordBoost 热词每个字符的加分，为对数概率，值越大越倾向于识别为热词
+	HotwordBoost float64 `json:"hotword_boost"`
+	// CharWhitelist 只识别这些字符，与CharSets都为空时不限制
+	CharWhitelist string `json:"char_whitelist,omitempty"`
+	// CharBlacklist 不识别这些字符，优先于白名单和字符集
+	CharBlacklist string `json:"char_blacklist,omitempty"`
+	// CharSets 只识别这些命名字符集中的字符，与CharWhitelist取并集
+	CharSets []string `json:"char_sets,omitempty"`
+	// AutoRotate 识别前检测整页方向（0、90、180、270度）并旋转，只对整图识别生效
+	AutoRotate bool `json:"auto_rotate,omitempty"`
+	// Tile 长边超过TileSize+TileOverlap的大图分块识别，分块不缩放，MaxSideLen不生效
+	Tile bool `json:"tile,omitempty"`
+	// TileSize 分块的边长
+	TileSize int `json:"tile_size"`
+	// TileOverlap 相邻分块至少重叠的像素数，应大于文本行的高度
+	TileOverlap int `json:"tile_overlap"`
+	// Deskew 识别前检测文本行的倾斜角度并纠偏，只对整图识别生效
+	Deskew bool `json:"deskew,omitempty"`
+	// DeskewMapBack 纠偏后将坐标和结果图片映射回纠偏前的图片
+	DeskewMapBack bool `json:"deskew_map_back,omitempty"`
+	// Preprocess 识别前在Go侧执行的预处理步骤，按PreprocessSteps的顺序执行
+	Preprocess []string `json:"preprocess,omitempty"`
+}
+
+// DefaultDetectParams 返回默认检测参数
+func DefaultDetectParams() DetectParams {
+	return DetectParams{
+		Padding:        kDefaultPadding,
+		MaxSideLen:     kDefaultMaxSideLen,
+		BoxScoreThresh: kDefaultBoxScoreThresh,
+		BoxThresh:      kDefaultBoxThresh,
+		UnClipRatio:    kDefaultUnClipRatio,
+		DoAngle:        true,
+		MostAngle:      true,
+		HotwordBoost:   kDefaultHotwordBoost,
+		TileSize:       kDefaultTileSize,
+		TileOverlap:    kDefaultTileOverlap,
+	}
+}
+
+// Validate 检查参数范围
+func (p DetectParams) Validate() error {
+	if p.Padding < 0 || p.Padding > 200 {
+		return fmt.Errorf("padding取值范围为0~200: %d", p.Padding)
+	}
+	if p.MaxSideLen < 0 || p.MaxSideLen > 8192 {
+		return fmt.Errorf("max_side_len取值范围为0~8192: %d", p.MaxSideLen)
+	}
+	if p.BoxScoreThresh <= 0 || p.BoxScoreThresh >= 1 {
+		return fmt.Errorf("box_score_thresh取值范围为(0, 1): %g", p.BoxScoreThresh)
+	}
+	if p.BoxThresh <= 0 || p.BoxThresh >= 1 {
+		return fmt.Errorf("box_thresh取值范围为(0, 1): %g", p.BoxThresh)
+	}
+	if p.UnClipRatio < 1 || p.UnClipRatio > 4 {
+		return fmt.Errorf("un_clip_ratio取值范围为1~4: %g", p.UnClipRatio)
+	}
+	if p.ResultImage != "" && p.ResultImage != kImageFormatPNG && p.ResultImage != kImageFormatJPEG {
+		return fmt.Errorf("image_format只支持png、jpeg: %s", p.ResultImage)
+	}
+	if p.TopK < 0 || p.TopK > kMaxTopK {
+		return fmt.Errorf("top_k取值范围为0~%d: %d", kMaxTopK, p.TopK)
+	}
+	if p.BeamWidth < 0 || p.BeamWidth > kMaxBeamWidth {
+		return fmt.Errorf("beam_width取值范围为0~%d: %d", kMaxBeamWidth, p.BeamWidth)
+	}
+	if len(p.Hotwords) > kMaxHotwords {
+		return fmt.Errorf("热词数量不能超过%d: %d", kMaxHotwords, len(p.Hotwords))
+	}
+	for _, hotword := range p.Hotwords {
+		if n := utf8.RuneCountInString(hotword); n == 0 || n > kMaxHotwordLen {
+			return fmt.Errorf("热词长度取值范围为1~%d: %q", kMaxHotwordLen, hotword)
+		}
+	}
+	if p.HotwordBoost < 0 || p.HotwordBoost > 10 {
+		return fmt.Errorf("hotword_boost取值范围为0~10: %g", p.HotwordBoost)
+	}
+	if err := validateCharList("char_whitelist", p.CharWhitelist); err != nil {
+		return err
+	}
+	if err := validateCharList("char_blacklist", p.CharBlacklist); err != nil {
+		return err
+	}
+	for _, name := range p.CharSets {
+		if !isCharSet(name) {
+			return fmt.Errorf("未知的字符集: %s，可选值: %s", name, strings.Join(charSets, ", "))
+		}
+	}
+	// 分块参数只在开启分块时使用
+	if p.Tile {
+		if p.TileSize < kMinTileSize || p.TileSize > kMaxTileSize {
+			return fmt.Errorf("tile_size取值范围为%d~%d: %d", kMinTileSize, kMaxTileSize, p.TileSize)
+		}
+		if p.TileOverlap < 0 || p.TileOverlap > p.TileSize/2 {
+			return fmt.Errorf("tile_overlap取值范围为0~%d: %d", p.TileSize/2, p.TileOverlap)
+		}
+	}
+	for _, name := range p.Preprocess {
+		if !isPreprocessStep(name) {
+			return fmt.Errorf("未知的预处理步骤: %s，可选值: %s", name, strings.Join(PreprocessSteps(), ", "))
+		}
+	}
+	return nil
+}
+
+// validateCharList 检查字符白名单、黑名单
+func validateCharList(name, chars string) error {
+	if !utf8.ValidString(chars) {
+		return fmt.Errorf("%s不是有效的UTF-8字符串", name)
+	}
+	if n := utf8.RuneCountInString(chars); n > kMaxCharListLen {
+		return fmt.Errorf("%s最多%d个字符: %d", name, kMaxCharListLen, n)
+	}
+	return nil
+}
+
+// detectPresets 命名的参数预设，在默认参数的基础上调整部分参数
+var detectPresets = map[string]func(p *DetectParams){
+	// fast 缩小图片并跳过方向检测，适合清晰的截图
+	"fast": func(p *DetectParams) {
+		p.MaxSideLen = 640
+		p.DoAngle = false
+		p.MostAngle = false
+	},
+	// accurate 使用更大的检测尺寸和更低的置信度阈值，提高召回率
+	"accurate": func(p *DetectParams) {
+		p.MaxSideLen = 1600
+		p.BoxScoreThresh = 0.5
+		p.DoAngle = true
+		p.MostAngle = true
+	},
+	// small-text 不缩放原图并收紧文本框，适合大图中的小字
+	"small-text": func(p *DetectParams) {
+		p.MaxSideLen = 0
+		p.BoxScoreThresh = 0.5
+		p.BoxThresh = 0.25
+		p.UnClipRatio = 1.6
+	},
+}
+
+// DetectPresets 返回所有预设名称
+func DetectPresets() []string {
+	names := make([]string, 0, len(detectPresets))
+	for name := range detectPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectOptions 请求中可选的检测参数，未设置的字段使用预设或服务默认值
+type DetectOptions struct {
+	Preset         string   `json:"preset" form:"preset"`
+	Padding        *int     `json:"padding" form:"padding"`
+	MaxSideLen     *int     `json:"max_side_len" form:"max_side_len"`
+	BoxScoreThresh *float64 `json:"box_score_thresh" form:"box_score_thresh"`
+	BoxThresh      *float64 `json:"box_thresh" form:"box_thresh"`
+	UnClipRatio    *float64 `json:"un_clip_ratio" form:"un_clip_ratio"`
+	DoAngle        *bool    `json:"do_angle" form:"do_angle"`
+	MostAngle      *bool    `json:"most_angle" form:"most_angle"`
+	BeamWidth      *int     `json:"beam_width" form:"beam_width"`
+	// Hotwords 追加到模型配置的热词之后
+	Hotwords     []string `json:"hotwords" form:"hotwords"`
+	HotwordBoost *float64 `json:"hotword_boost" form:"hotword_boost"`
+	// CharWhitelist、CharBlacklist、CharSets 设置后替换模型配置中的值
+	CharWhitelist *string  `json:"char_whitelist" form:"char_whitelist"`
+	CharBlacklist *string  `json:"char_blacklist" form:"char_blacklist"`
+	CharSets      []string `json:"char_sets" form:"char_sets"`
+	AutoRotate    *bool    `json:"auto_rotate" form:"auto_rotate"`
+	Tile          *bool    `json:"tile" form:"tile"`
+	TileSize      *int     `json:"tile_size" form:"tile_size"`
+	TileOverlap   *int     `json:"tile_overlap" form:"tile_overlap"`
+	Deskew        *bool    `json:"deskew" form:"deskew"`
+	DeskewMapBack *bool    `json:"deskew_map_back" form:"deskew_map_back"`
+	// Preprocess 设置后替换模型配置中的预处理步骤，空数组表示不预处理
+	Preprocess []string `json:"preprocess" form:"preprocess"`
+}
+
+// Resolve 依次应用默认参数、预设和请求中的参数，并检查结果，参数无效时返回ErrInvalidArgument
+func (o DetectOptions) Resolve(defaults DetectParams) (DetectParams, error) {
+	params := defaults
+
+	if o.Preset != "" {
+		preset, ok := detectPresets[o.Preset]
+		if !ok {
+			return params, &OcrError{Code: ErrCodeInvalidArgument,
+				Message: fmt.Sprintf("未知的参数预设: %s，可选值: %s", o.Preset, strings.Join(DetectPresets(), ", "))}
+		}
+		preset(&params)
+	}
+
+	if o.Padding != nil {
+		params.Padding = *o.Padding
+	}
+	if o.MaxSideLen != nil {
+		params.MaxSideLen = *o.MaxSideLen
+	}
+	if o.BoxScoreThresh != nil {
+		params.BoxScoreThresh = *o.BoxScoreThresh
+	}
+	if o.BoxThresh != nil {
+		params.BoxThresh = *o.BoxThresh
+	}
+	if o.UnClipRatio != nil {
+		params.UnClipRatio = *o.UnClipRatio
+	}
+	if o.DoAngle != nil {
+		params.DoAngle = *o.DoAngle
+	}
+	if o.MostAngle != nil {
+		params.MostAngle = *o.MostAngle
+	}
+	if o.BeamWidth != nil {
+		params.BeamWidth = *o.BeamWidth
+	}
+	if len(o.Hotwords) > 0 {
+		// 复制一份，避免修改模型配置的热词
+		params.Hotwords = append(append([]string(nil), params.Hotwords...), o.Hotwords...)
+	}
+	if o.HotwordBoost != nil {
+		params.HotwordBoost = *o.HotwordBoost
+	}
+	if o.CharWhitelist != nil {
+		params.CharWhitelist = *o.CharWhitelist
+	}
+	if o.CharBlacklist != nil {
+		params.CharBlacklist = *o.CharBlacklist
+	}
+	if o.CharSets != nil {
+		params.CharSets = o.CharSets
+	}
+	if o.AutoRotate != nil {
+		params.AutoRotate = *o.AutoRotate
+	}
+	if o.Tile != nil {
+		params.Tile = *o.Tile
+	}
+	if o.TileSize != nil {
+		params.TileSize = *o.TileSize
+	}
+	if o.TileOverlap != nil {
+		params.TileOverlap = *o.TileOverlap
+	}
+	if o.Deskew != nil {
+		params.Deskew = *o.Deskew
+	}
+	if o.DeskewMapBack != nil {
+		params.DeskewMapBack = *o.DeskewMapBack
+	}
+	if o.Preprocess != nil {
+		params.Preprocess = o.Preprocess
+	}
+
+	if err := params.Validate(); err != nil {
+		// 错误码为ErrCodeInvalidArgument，消息不加前缀，由调用方说明是哪里的参数
+		return params, &OcrError{Code: ErrCodeInvalidArgument, Message: err.Error()}
+	}
+	return params, nil
+}
@@ -0,0 +1,153 @@
+package src
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectOptionsResolve(t *testing.T) {
+	defaults := DefaultDetectParams()
+
+	t.Run("empty options use defaults", func(t *testing.T) {
+		params, err := DetectOptions{}.Resolve(defaults)
+		assert.NoError(t, err)
+		assert.Equal(t, defaults, params)
+	})
+
+	t.Run("explicit fields override preset", func(t *testing.T) {
+		maxSideLen := 800
+		doAngle := true
+		params, err := DetectOptions{Preset: "fast", MaxSideLen: &maxSideLen, DoAngle: &doAngle}.Resolve(defaults)
+		assert.NoError(t, err)
+		assert.Equal(t, 800, params.MaxSideLen)
+		assert.True(t, params.DoAngle)
+		assert.False(t, params.MostAngle)
+		assert.Equal(t, defaults.Padding, params.Padding)
+	})
+
+	t.Run("unknown preset", func(t *testing.T) {
+		_, err := DetectOptions{Preset: "turbo"}.Resolve(defaults)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "small-text")
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		padding := -1
+		maxSideLen := 10000
+		zero := 0.0
+		ratio := 0.5
+		cases := []DetectOptions{
+			{Padding: &padding},
+			{MaxSideLen: &maxSideLen},
+			{BoxScoreThresh: &zero},
+			{BoxThresh: &zero},
+			{UnClipRatio: &ratio},
+		}
+		for _, options := range cases {
+			_, err := options.Resolve(defaults)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("result image format", func(t *testing.T) {
+		withImage := defaults
+		withImage.ResultImage = "gif"
+		_, err := DetectOptions{}.Resolve(withImage)
+		assert.ErrorContains(t, err, "image_format")
+
+		withImage.ResultImage = "jpeg"
+		params, err := DetectOptions{}.Resolve(withImage)
+		assert.NoError(t, err)
+		assert.Equal(t, "jpeg", params.ResultImage)
+	})
+
+	t.Run("top k", func(t *testing.T) {
+		withTopK := defaults
+		withTopK.TopK = kMaxTopK + 1
+		_, err := DetectOptions{}.Resolve(withTopK)
+		assert.ErrorContains(t, err, "top_k")
+
+		withTopK.TopK = 3
+		params, err := DetectOptions{}.Resolve(withTopK)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, params.TopK)
+	})
+
+	t.Run("beam search", func(t *testing.T) {
+		beamWidth := 8
+		withHotwords := defaults
+		withHotwords.Hotwords = []string{"营业执照"}
+		params, err := DetectOptions{BeamWidth: &beamWidth, Hotwords: []string{"统一社会信用代码"}}.Resolve(withHotwords)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, params.BeamWidth)
+		assert.Equal(t, kDefaultHotwordBoost, params.HotwordBoost)
+		assert.Equal(t, []string{"营业执照", "统一社会信用代码"}, params.Hotwords)
+		// 请求中的热词不能修改模型配置的热词
+		assert.Equal(t, []string{"营业执照"}, withHotwords.Hotwords)
+
+		beamWidth = kMaxBeamWidth + 1
+		_, err = DetectOptions{BeamWidth: &beamWidth}.Resolve(defaults)
+		assert.ErrorContains(t, err, "beam_width")
+
+		_, err = DetectOptions{Hotwords: []string{""}}.Resolve(defaults)
+		assert.ErrorContains(t, err, "热词")
+
+		boost := -1.0
+		_, err = DetectOptions{HotwordBoost: &boost}.Resolve(defaults)
+		assert.ErrorContains(t, err, "hotword_boost")
+	})
+
+	t.Run("char sets", func(t *testing.T) {
+		withCharSets := defaults
+		withCharSets.CharSets = []string{"digits"}
+		whitelist := "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		params, err := DetectOptions{CharWhitelist: &whitelist}.Resolve(withCharSets)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"digits"}, params.CharSets)
+		assert.Equal(t, whitelist, params.CharWhitelist)
+
+		params, err = DetectOptions{CharSets: []string{"alnum", "hanzi"}}.Resolve(withCharSets)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alnum", "hanzi"}, params.CharSets)
+
+		_, err = DetectOptions{CharSets: []string{"emoji"}}.Resolve(defaults)
+		assert.ErrorContains(t, err, "未知的字符集")
+
+		invalid := "\xff"
+		_, err = DetectOptions{CharBlacklist: &invalid}.Resolve(defaults)
+		assert.ErrorContains(t, err, "char_blacklist")
+	})
+
+	t.Run("tile", func(t *testing.T) {
+		tile := true
+		tileSize := 800
+		params, err := DetectOptions{Tile: &tile, TileSize: &tileSize}.Resolve(defaults)
+		assert.NoError(t, err)
+		assert.True(t, params.Tile)
+		assert.Equal(t, 800, params.TileSize)
+		assert.Equal(t, kDefaultTileOverlap, params.TileOverlap)
+
+		tileSize = kMaxTileSize + 1
+		_, err = DetectOptions{Tile: &tile, TileSize: &tileSize}.Resolve(defaults)
+		assert.ErrorContains(t, err, "tile_size")
+
+		overlap := kDefaultTileSize/2 + 1
+		_, err = DetectOptions{Tile: &tile, TileOverlap: &overlap}.Resolve(defaults)
+		assert.ErrorContains(t, err, "tile_overlap")
+
+		// 未开启分块时不检查分块参数
+		zero := 0
+		_, err = DetectOptions{TileSize: &zero, TileOverlap: &overlap}.Resolve(defaults)
+		assert.NoError(t, err)
+	})
+}
+
+func TestDetectPresets(t *testing.T) {
+	assert.Equal(t, []string{"accurate", "fast", "small-text"}, DetectPresets())
+	for _, name := range DetectPresets() {
+		params, err := DetectOptions{Preset: name}.Resolve(DefaultDetectParams())
+		assert.NoError(t, err, name)
+		assert.NoError(t, params.Validate(), name)
+	}
+}
@@ -0,0 +1,164 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// ErrPoolBusy 所有实例都在工作且等待队列已满
+var ErrPoolBusy = errors.New("OCR引擎繁忙，请稍后重试")
+
+// EnginePool 由多个独立引擎实例组成的引擎池
+//
+// 每次识别独占一个实例，实例都忙时请求进入有界的等待队列，
+// 队列满时立即返回ErrPoolBusy，避免请求无限堆积。
+type EnginePool struct {
+	engines []Engine
+	idle    chan Engine
+	queue   chan struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewEnginePool 使用factory创建size个实例组成引擎池，queueSize为等待队列长度
+//
+// 任意实例创建失败时，已创建的实例会被关闭并返回错误。
+func NewEnginePool(size, queueSize int, factory func(index int) (Engine, error)) (*EnginePool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("引擎池大小必须大于0: %d", size)
+	}
+	if queueSize < 0 {
+		return nil, fmt.Errorf("等待队列长度不能小于0: %d", queueSize)
+	}
+
+	pool := &EnginePool{
+		engines: make([]Engine, 0, size),
+		idle:    make(chan Engine, size),
+		queue:   make(chan struct{}, queueSize),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		engine, err := factory(i)
+		if err != nil {
+			for _, created := range pool.engines {
+				created.Close()
+			}
+			return nil, fmt.Errorf("创建第%d个OCR实例失败: %w", i+1, err)
+		}
+		pool.engines = append(pool.engines, engine)
+		pool.idle <- engine
+	}
+
+	return pool, nil
+}
+
+func (p *EnginePool) RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error) {
+	engine, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(engine)
+
+	return engine.RecognizeBytes(ctx, data, params)
+}
+
+func (p *EnginePool) RecognizeImage(ctx context.Context, img image.Image, params DetectParams) (*OCRResultData, error) {
+	engine, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(engine)
+
+	return engine.RecognizeImage(ctx, img, params)
+}
+
+func (p *EnginePool) RecognizeLines(ctx context.Context, lines []image.Image, params DetectParams) (*OCRResultData, error) {
+	engine, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(engine)
+
+	return engine.RecognizeLines(ctx, lines, params)
+}
+
+func (p *EnginePool) DetectBytes(ctx context.Context, data []byte, params DetectParams) (*DetectResultData, error) {
+	engine, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(engine)
+
+	return engine.DetectBytes(ctx, data, params)
+}
+
+func (p *EnginePool) DetectImage(ctx context.Context, img image.Image, params DetectParams) (*DetectResultData, error) {
+	engine, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(engine)
+
+	return engine.DetectImage(ctx, img, params)
+}
+
+// Close 等待进行中的识别完成后关闭所有实例
+func (p *EnginePool) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.done)
+		for range p.engines {
+			engine := <-p.idle
+			if closeErr := engine.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
+func (p *EnginePool) Info() EngineInfo {
+	info := p.engines[0].Info()
+	info.PoolSize = len(p.engines)
+	return info
+}
+
+// acquire 获取一个空闲实例，没有空闲实例时进入等待队列
+func (p *EnginePool) acquire(ctx context.Context) (Engine, error) {
+	select {
+	case <-p.done:
+		return nil, ErrEngineClosed
+	default:
+	}
+
+	select {
+	case engine := <-p.idle:
+		return engine, nil
+	default:
+	}
+
+	select {
+	case p.queue <- struct{}{}:
+	default:
+		return nil, ErrPoolBusy
+	}
+	defer func() { <-p.queue }()
+
+	select {
+	case engine := <-p.idle:
+		return engine, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, ErrEngineClosed
+	}
+}
+
+// release 归还实例
+func (p *EnginePool) release(engine Engine) {
+	p.idle <- engine
+}
@@ -0,0 +1,185 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"image"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingEngine 识别时阻塞直到release被关闭，用于观察并发行为
+type blockingEngine struct {
+	FakeEngine
+	started chan struct{}
+	release chan struct{}
+	active  *int32
+	maxSeen *int32
+}
+
+func (e *blockingEngine) RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error) {
+	n := atomic.AddInt32(e.active, 1)
+	defer atomic.AddInt32(e.active, -1)
+	for {
+		seen := atomic.LoadInt32(e.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(e.maxSeen, seen, n) {
+			break
+		}
+	}
+	e.started <- struct{}{}
+	<-e.release
+	return e.FakeEngine.RecognizeBytes(ctx, data, params)
+}
+
+func newBlockingPool(t *testing.T, size, queueSize int) (*EnginePool, []*blockingEngine, chan struct{}, chan struct{}, *int32) {
+	started := make(chan struct{}, 100)
+	release := make(chan struct{})
+	var active, maxSeen int32
+	var engines []*blockingEngine
+
+	pool, err := NewEnginePool(size, queueSize, func(index int) (Engine, error) {
+		engine := &blockingEngine{started: started, release: release, active: &active, maxSeen: &maxSeen}
+		engines = append(engines, engine)
+		return engine, nil
+	})
+	assert.NoError(t, err)
+	return pool, engines, started, release, &maxSeen
+}
+
+func TestEnginePoolConcurrency(t *testing.T) {
+	pool, engines, started, release, maxSeen := newBlockingPool(t, 2, 10)
+	defer pool.Close()
+
+	const requests = 6
+	var wg sync.WaitGroup
+	results := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := pool.RecognizeBytes(context.Background(), []byte("test"), DefaultDetectParams())
+			if err == nil && len(result.Texts) != 1 {
+				err = errors.New("unexpected result")
+			}
+			results <- err
+		}()
+	}
+
+	// 两个实例都开始工作后释放
+	<-started
+	<-started
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(maxSeen))
+	assert.Equal(t, requests, engines[0].Calls()+engines[1].Calls())
+}
+
+func TestEnginePoolQueueFull(t *testing.T) {
+	pool, _, started, release, _ := newBlockingPool(t, 1, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pool.RecognizeBytes(context.Background(), []byte("busy"), DefaultDetectParams())
+	}()
+	<-started
+
+	// 第二个请求进入等待队列
+	go func() {
+		defer wg.Done()
+		pool.RecognizeBytes(context.Background(), []byte("queued"), DefaultDetectParams())
+	}()
+	assert.Eventually(t, func() bool { return len(pool.queue) == 1 }, time.Second, time.Millisecond)
+
+	// 队列已满，第三个请求立即返回繁忙
+	_, err := pool.RecognizeBytes(context.Background(), []byte("rejected"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrPoolBusy)
+
+	close(release)
+	wg.Wait()
+	assert.NoError(t, pool.Close())
+}
+
+func TestEnginePoolContextCanceled(t *testing.T) {
+	pool, _, started, release, _ := newBlockingPool(t, 1, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.RecognizeBytes(context.Background(), []byte("busy"), DefaultDetectParams())
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.RecognizeBytes(ctx, []byte("waiting"), DefaultDetectParams())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	<-done
+	assert.NoError(t, pool.Close())
+}
+
+func TestEnginePoolClose(t *testing.T) {
+	var engines []*FakeEngine
+	pool, err := NewEnginePool(3, 0, func(index int) (Engine, error) {
+		engine := NewFakeEngine()
+		engines = append(engines, engine)
+		return engine, nil
+	})
+	assert.NoError(t, err)
+
+	result, err := pool.RecognizeImage(context.Background(), image.NewGray(image.Rect(0, 0, 8, 8)), DefaultDetectParams())
+	assert.NoError(t, err)
+	assert.Len(t, result.Texts, 1)
+
+	info := pool.Info()
+	assert.Equal(t, "fake", info.Name)
+	assert.Equal(t, 3, info.PoolSize)
+
+	assert.NoError(t, pool.Close())
+	assert.NoError(t, pool.Close())
+	for _, engine := range engines {
+		_, err := engine.RecognizeBytes(context.Background(), []byte("test"), DefaultDetectParams())
+		assert.ErrorIs(t, err, ErrEngineClosed)
+	}
+
+	_, err = pool.RecognizeBytes(context.Background(), []byte("test"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrEngineClosed)
+}
+
+func TestNewEnginePoolErrors(t *testing.T) {
+	t.Run("invalid size", func(t *testing.T) {
+		_, err := NewEnginePool(0, 1, func(index int) (Engine, error) { return NewFakeEngine(), nil })
+		assert.Error(t, err)
+	})
+
+	t.Run("factory error closes created engines", func(t *testing.T) {
+		var created []*FakeEngine
+		_, err := NewEnginePool(3, 1, func(index int) (Engine, error) {
+			if index == 2 {
+				return nil, errors.New("模型加载失败")
+			}
+			engine := NewFakeEngine()
+			created = append(created, engine)
+			return engine, nil
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "模型加载失败")
+		assert.Len(t, created, 2)
+		for _, engine := range created {
+			_, err := engine.RecognizeBytes(context.Background(), []byte("test"), DefaultDetectParams())
+			assert.ErrorIs(t, err, ErrEngineClosed)
+		}
+	})
+}
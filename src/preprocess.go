@@ -0,0 +1,510 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// 预处理步骤名称
+const (
+	kPreprocessFlatten   = "flatten"
+	kPreprocessUpscale   = "upscale"
+	kPreprocessGrayscale = "grayscale"
+	kPreprocessDenoise   = "denoise"
+	kPreprocessContrast  = "contrast"
+	kPreprocessCLAHE     = "clahe"
+	kPreprocessBinarize  = "binarize"
+)
+
+const (
+	// kUpscaleSide 长边小于该值的图片放大到不小于该值
+	kUpscaleSide = 960
+	// kMaxUpscale 最大放大倍数
+	kMaxUpscale = 4
+	// kContrastClip 自动对比度时两端各忽略的像素比例
+	kContrastClip = 0.01
+	// kCLAHEGrid CLAHE在每个方向上划分的块数
+	kCLAHEGrid = 8
+	// kCLAHEClipLimit CLAHE直方图的裁剪倍数，相对于平均每个灰度级的像素数
+	kCLAHEClipLimit = 2.0
+	// kBinarizeRatio 自适应二值化时低于邻域均值该比例的像素为黑色
+	kBinarizeRatio = 0.15
+)
+
+// preprocessJob 预处理过程中的图片状态
+type preprocessJob struct {
+	img image.Image
+	// orientation 原图的EXIF方向，解码时已按该方向旋转，没有时为0
+	orientation int
+	// scale upscale步骤的放大倍数，识别结果的坐标需要除以该值
+	scale int
+	// size 预处理前的图片尺寸，放大后的结果图片缩小回该尺寸
+	size image.Point
+	// applied 实际改变了图片的预处理步骤
+	applied []string
+}
+
+// preprocessors 所有预处理步骤，按该顺序执行，与请求中的顺序无关；步骤未改变图片时返回false
+var preprocessors = []struct {
+	name  string
+	apply func(job *preprocessJob) bool
+}{
+	{kPreprocessFlatten, flattenAlpha},
+	{kPreprocessUpscale, upscaleSmall},
+	{kPreprocessGrayscale, func(job *preprocessJob) bool {
+		if _, ok := job.img.(*image.Gray); ok {
+			return false
+		}
+		job.img = toGray(job.img)
+		return true
+	}},
+	{kPreprocessDenoise, medianDenoise},
+	{kPreprocessContrast, stretchContrast},
+	{kPreprocessCLAHE, equalizeCLAHE},
+	{kPreprocessBinarize, binarizeAdaptive},
+}
+
+// PreprocessSteps 返回所有预处理步骤的名称，按执行顺序排列
+func PreprocessSteps() []string {
+	names := make([]string, 0, len(preprocessors))
+	for _, p := range preprocessors {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+func isPreprocessStep(name string) bool {
+	for _, p := range preprocessors {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// preprocessImage 按EXIF方向解码图片并执行steps中的预处理步骤
+func preprocessImage(data []byte, steps []string) (*preprocessJob, error) {
+	img, orientation, err := decodeOrientedImage(data)
+	if err != nil {
+		return nil, err
+	}
+	return preprocessDecoded(img, orientation, steps), nil
+}
+
+// preprocessDecoded 对已解码的图片执行steps中的预处理步骤，orientation为解码时已应用的EXIF方向
+//
+// 各步骤都输出新的图片，不修改img。
+func preprocessDecoded(img image.Image, orientation int, steps []string) *preprocessJob {
+	job := &preprocessJob{img: img, orientation: orientation, scale: 1, size: img.Bounds().Size(), applied: []string{}}
+	for _, p := range preprocessors {
+		if containsString(steps, p.name) && p.apply(job) {
+			job.applied = append(job.applied, p.name)
+		}
+	}
+	return job
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleResultBoxes 将放大后图片上的坐标换算回原图坐标
+func scaleResultBoxes(blocks []OCRTextBlock, scale int) {
+	for i := range blocks {
+		scaleBoxPoints(blocks[i].BoxPoint, scale)
+		for _, charBox := range blocks[i].CharBoxes {
+			scaleBoxPoints(charBox.BoxPoint, scale)
+		}
+	}
+}
+
+func scaleBoxPoints(points []OCRBoxPoint, scale int) {
+	if scale <= 1 {
+		return
+	}
+	for i := range points {
+		points[i].X = int(math.Round(float64(points[i].X) / float64(scale)))
+		points[i].Y = int(math.Round(float64(points[i].Y) / float64(scale)))
+	}
+}
+
+// scaleResultImage 将放大后图片上标注的结果图片缩小回预处理前的尺寸，与换算后的坐标一致
+func scaleResultImage(data []byte, format string, size image.Point) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 结果图片: %v", ErrImageDecode, err)
+	}
+	dst := image.NewNRGBA(image.Rectangle{Max: size})
+	draw.ApproxBiLinear.Scale(dst, dst.Rect, img, img.Bounds(), draw.Src, nil)
+	var buf bytes.Buffer
+	if format == kImageFormatJPEG {
+		err = jpeg.Encode(&buf, dst, nil)
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flattenAlpha 将带透明通道的图片合成到白色背景上
+func flattenAlpha(job *preprocessJob) bool {
+	if opaque, ok := job.img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return false
+	}
+	src := toNRGBA(job.img)
+	dst := image.NewNRGBA(src.Rect)
+	for i := 0; i < len(src.Pix); i += 4 {
+		a := uint32(src.Pix[i+3])
+		dst.Pix[i] = blendWhite(uint32(src.Pix[i]), a)
+		dst.Pix[i+1] = blendWhite(uint32(src.Pix[i+1]), a)
+		dst.Pix[i+2] = blendWhite(uint32(src.Pix[i+2]), a)
+		dst.Pix[i+3] = 255
+	}
+	job.img = dst
+	return true
+}
+
+// upscaleSmall 按整数倍双线性放大长边小于kUpscaleSide的图片，小字在检测模型中更容易被检出
+func upscaleSmall(job *preprocessJob) bool {
+	bounds := job.img.Bounds()
+	longSide := bounds.Dx()
+	if bounds.Dy() > longSide {
+		longSide = bounds.Dy()
+	}
+	if longSide == 0 {
+		return false
+	}
+	scale := (kUpscaleSide + longSide - 1) / longSide
+	if scale > kMaxUpscale {
+		scale = kMaxUpscale
+	}
+	if scale < 2 {
+		return false
+	}
+	src := toNRGBA(job.img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w*scale, h*scale))
+	for dy := 0; dy < h*scale; dy++ {
+		fy := (float64(dy)+0.5)/float64(scale) - 0.5
+		y0, wy := splitCoord(fy, h)
+		for dx := 0; dx < w*scale; dx++ {
+			fx := (float64(dx)+0.5)/float64(scale) - 0.5
+			x0, wx := splitCoord(fx, w)
+			x1, y1 := x0+1, y0+1
+			if x1 >= w {
+				x1 = w - 1
+			}
+			if y1 >= h {
+				y1 = h - 1
+			}
+			p00, p01 := src.PixOffset(x0, y0), src.PixOffset(x1, y0)
+			p10, p11 := src.PixOffset(x0, y1), src.PixOffset(x1, y1)
+			d := dst.PixOffset(dx, dy)
+			for c := 0; c < 4; c++ {
+				top := float64(src.Pix[p00+c])*(1-wx) + float64(src.Pix[p01+c])*wx
+				bottom := float64(src.Pix[p10+c])*(1-wx) + float64(src.Pix[p11+c])*wx
+				dst.Pix[d+c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+	job.img = dst
+	job.scale *= scale
+	return true
+}
+
+// splitCoord 将浮点坐标拆分为不越界的整数坐标及插值权重
+func splitCoord(f float64, size int) (int, float64) {
+	if f <= 0 {
+		return 0, 0
+	}
+	i := int(f)
+	if i >= size-1 {
+		return size - 1, 0
+	}
+	return i, f - float64(i)
+}
+
+// medianDenoise 3x3中值滤波，去除椒盐噪点，保留文字边缘；没有像素变化时不替换图片
+func medianDenoise(job *preprocessJob) bool {
+	switch src := job.img.(type) {
+	case *image.Gray:
+		gray := toGray(src)
+		dst := image.NewGray(gray.Rect)
+		medianFilter(dst.Pix, gray.Pix, gray.Rect.Dx(), gray.Rect.Dy(), gray.Stride, 1, 1)
+		if bytes.Equal(dst.Pix, gray.Pix) {
+			return false
+		}
+		job.img = dst
+	default:
+		nrgba := toNRGBA(src)
+		dst := image.NewNRGBA(nrgba.Rect)
+		medianFilter(dst.Pix, nrgba.Pix, nrgba.Rect.Dx(), nrgba.Rect.Dy(), nrgba.Stride, 4, 3)
+		// 透明通道不滤波
+		for i := 3; i < len(dst.Pix); i += 4 {
+			dst.Pix[i] = nrgba.Pix[i]
+		}
+		if bytes.Equal(dst.Pix, nrgba.Pix) {
+			return false
+		}
+		job.img = dst
+	}
+	return true
+}
+
+// medianFilter 对每个像素的前channels个通道做3x3中值滤波，边缘像素使用最近的像素补齐
+func medianFilter(dst, src []byte, w, h, stride, pixelSize, channels int) {
+	var window [9]byte
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			for c := 0; c < channels; c++ {
+				n := 0
+				for ky := y - 1; ky <= y+1; ky++ {
+					for kx := x - 1; kx <= x+1; kx++ {
+						window[n] = src[clampInt(ky, 0, h-1)*stride+clampInt(kx, 0, w-1)*pixelSize+c]
+						n++
+					}
+				}
+				// 插入排序，9个值比sort包快
+				for i := 1; i < len(window); i++ {
+					for j := i; j > 0 && window[j] < window[j-1]; j-- {
+						window[j], window[j-1] = window[j-1], window[j]
+					}
+				}
+				dst[y*stride+x*pixelSize+c] = window[4]
+			}
+		}
+	}
+}
+
+func clampInt(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// stretchContrast 自动对比度，将亮度两端各kContrastClip比例之外的范围拉伸到0~255，彩色图片各通道使用相同的映射
+func stretchContrast(job *preprocessJob) bool {
+	var hist [256]int
+	gray := toGray(job.img)
+	for _, v := range gray.Pix {
+		hist[v]++
+	}
+	clip := int(float64(len(gray.Pix)) * kContrastClip)
+	low, high := 0, 255
+	for n := 0; low < 255 && n+hist[low] <= clip; low++ {
+		n += hist[low]
+	}
+	for n := 0; high > 0 && n+hist[high] <= clip; high-- {
+		n += hist[high]
+	}
+	if high <= low || (low == 0 && high == 255) {
+		return false
+	}
+	var lut [256]byte
+	for v := range lut {
+		lut[v] = uint8(clampInt((v-low)*255/(high-low), 0, 255))
+	}
+	if _, ok := job.img.(*image.Gray); ok {
+		for i, v := range gray.Pix {
+			gray.Pix[i] = lut[v]
+		}
+		job.img = gray
+		return true
+	}
+	src := toNRGBA(job.img)
+	dst := image.NewNRGBA(src.Rect)
+	for i := 0; i < len(src.Pix); i += 4 {
+		dst.Pix[i] = lut[src.Pix[i]]
+		dst.Pix[i+1] = lut[src.Pix[i+1]]
+		dst.Pix[i+2] = lut[src.Pix[i+2]]
+		dst.Pix[i+3] = src.Pix[i+3]
+	}
+	job.img = dst
+	return true
+}
+
+// equalizeCLAHE 限制对比度的自适应直方图均衡，改善光照不均的照片，输出灰度图
+func equalizeCLAHE(job *preprocessJob) bool {
+	gray := toGray(job.img)
+	w, h := gray.Rect.Dx(), gray.Rect.Dy()
+	gridX, gridY := kCLAHEGrid, kCLAHEGrid
+	if w < gridX {
+		gridX = w
+	}
+	if h < gridY {
+		gridY = h
+	}
+	if gridX == 0 || gridY == 0 {
+		return false
+	}
+	tileW, tileH := (w+gridX-1)/gridX, (h+gridY-1)/gridY
+
+	// 每个块裁剪后的直方图的累积分布，作为该块中心的映射
+	luts := make([][256]byte, gridX*gridY)
+	for ty := 0; ty < gridY; ty++ {
+		for tx := 0; tx < gridX; tx++ {
+			var hist [256]int
+			x0, y0 := tx*tileW, ty*tileH
+			x1, y1 := clampInt(x0+tileW, 0, w), clampInt(y0+tileH, 0, h)
+			for y := y0; y < y1; y++ {
+				for _, v := range gray.Pix[y*gray.Stride+x0 : y*gray.Stride+x1] {
+					hist[v]++
+				}
+			}
+			pixels := (x1 - x0) * (y1 - y0)
+			if pixels <= 0 {
+				continue
+			}
+			limit := int(kCLAHEClipLimit * float64(pixels) / 256)
+			if limit < 1 {
+				limit = 1
+			}
+			excess := 0
+			for v := range hist {
+				if hist[v] > limit {
+					excess += hist[v] - limit
+					hist[v] = limit
+				}
+			}
+			for v := range hist {
+				hist[v] += excess / 256
+				if v < excess%256 {
+					hist[v]++
+				}
+			}
+			sum := 0
+			lut := &luts[ty*gridX+tx]
+			for v := range hist {
+				sum += hist[v]
+				lut[v] = uint8(clampInt(sum*255/pixels, 0, 255))
+			}
+		}
+	}
+
+	// 在相邻四个块的映射之间双线性插值，避免块边界
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		fy := (float64(y)+0.5)/float64(tileH) - 0.5
+		ty0 := clampInt(int(floorFloat(fy)), 0, gridY-1)
+		ty1 := clampInt(ty0+1, 0, gridY-1)
+		wy := clampFloat(fy-float64(ty0), 0, 1)
+		for x := 0; x < w; x++ {
+			fx := (float64(x)+0.5)/float64(tileW) - 0.5
+			tx0 := clampInt(int(floorFloat(fx)), 0, gridX-1)
+			tx1 := clampInt(tx0+1, 0, gridX-1)
+			wx := clampFloat(fx-float64(tx0), 0, 1)
+			v := gray.Pix[y*gray.Stride+x]
+			top := float64(luts[ty0*gridX+tx0][v])*(1-wx) + float64(luts[ty0*gridX+tx1][v])*wx
+			bottom := float64(luts[ty1*gridX+tx0][v])*(1-wx) + float64(luts[ty1*gridX+tx1][v])*wx
+			dst.Pix[y*dst.Stride+x] = uint8(top*(1-wy) + bottom*wy + 0.5)
+		}
+	}
+	job.img = dst
+	return true
+}
+
+func floorFloat(f float64) float64 {
+	i := float64(int(f))
+	if f < i {
+		return i - 1
+	}
+	return i
+}
+
+func clampFloat(v, low, high float64) float64 {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// binarizeAdaptive 按邻域均值自适应二值化（Bradley），对阴影、光照不均的照片比全局阈值稳定，输出灰度图
+func binarizeAdaptive(job *preprocessJob) bool {
+	gray := toGray(job.img)
+	w, h := gray.Rect.Dx(), gray.Rect.Dy()
+	if w == 0 || h == 0 {
+		return false
+	}
+	radius := w
+	if h > radius {
+		radius = h
+	}
+	radius = radius/32 + 7
+
+	// 积分图，integral[(y+1)*(w+1)+x+1]为左上角到(x, y)的像素和
+	integral := make([]int64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var rowSum int64
+		for x := 0; x < w; x++ {
+			rowSum += int64(gray.Pix[y*gray.Stride+x])
+			integral[(y+1)*(w+1)+x+1] = integral[y*(w+1)+x+1] + rowSum
+		}
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := clampInt(y-radius, 0, h-1), clampInt(y+radius, 0, h-1)
+		for x := 0; x < w; x++ {
+			x0, x1 := clampInt(x-radius, 0, w-1), clampInt(x+radius, 0, w-1)
+			count := int64((x1 - x0 + 1) * (y1 - y0 + 1))
+			sum := integral[(y1+1)*(w+1)+x1+1] - integral[y0*(w+1)+x1+1] -
+				integral[(y1+1)*(w+1)+x0] + integral[y0*(w+1)+x0]
+			if float64(int64(gray.Pix[y*gray.Stride+x])*count) <= float64(sum)*(1-kBinarizeRatio) {
+				dst.Pix[y*dst.Stride+x] = 0
+			} else {
+				dst.Pix[y*dst.Stride+x] = 255
+			}
+		}
+	}
+	job.img = dst
+	return true
+}
+
+// toNRGBA 转换为坐标从(0, 0)开始的NRGBA图片，已经是时直接返回，因此返回的图片不能修改
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// toGray 转换为坐标从(0, 0)开始的灰度图片，透明像素按白色背景合成，返回的图片可以修改
+func toGray(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	dst := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	if gray, ok := img.(*image.Gray); ok {
+		for y := 0; y < bounds.Dy(); y++ {
+			copy(dst.Pix[y*dst.Stride:], gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:bounds.Dx()])
+		}
+		return dst
+	}
+	pix, width, height := imageToRGB(img)
+	for i := 0; i < width*height; i++ {
+		dst.Pix[i] = color.GrayModel.Convert(color.RGBA{R: pix[i*3], G: pix[i*3+1], B: pix[i*3+2], A: 255}).(color.Gray).Y
+	}
+	return dst
+}
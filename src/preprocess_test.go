@@ -0,0 +1,146 @@
+package src
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestPreprocessImage(t *testing.T) {
+	t.Run("flatten and upscale", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+		data := encodePNG(t, src)
+
+		job, err := preprocessImage(data, []string{kPreprocessUpscale, kPreprocessFlatten})
+		require.NoError(t, err)
+		// 按固定顺序执行，与请求中的顺序无关
+		assert.Equal(t, []string{kPreprocessFlatten, kPreprocessUpscale}, job.applied)
+		assert.Equal(t, kMaxUpscale, job.scale)
+		assert.Equal(t, image.Rect(0, 0, 400, 200), job.img.Bounds())
+		r, g, b, a := job.img.At(10, 10).RGBA()
+		assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff, 0xffff}, []uint32{r, g, b, a})
+
+		// 足够大的图片不放大
+		job, err = preprocessImage(encodePNG(t, image.NewGray(image.Rect(0, 0, kUpscaleSide, 10))), []string{kPreprocessUpscale})
+		require.NoError(t, err)
+		assert.Equal(t, 1, job.scale)
+		assert.Empty(t, job.applied)
+	})
+
+	t.Run("grayscale pipeline", func(t *testing.T) {
+		// 左暗右亮的渐变背景上有一条深色横线
+		src := image.NewRGBA(image.Rect(0, 0, 64, 32))
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 64; x++ {
+				v := uint8(120 + x)
+				if y >= 14 && y < 18 {
+					v = 30
+				}
+				src.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+			}
+		}
+		src.SetRGBA(5, 5, color.RGBA{A: 255}) // 噪点
+
+		steps := []string{kPreprocessBinarize, kPreprocessDenoise, kPreprocessGrayscale, kPreprocessContrast}
+		job, err := preprocessImage(encodePNG(t, src), steps)
+		require.NoError(t, err)
+		assert.Equal(t, []string{kPreprocessGrayscale, kPreprocessDenoise, kPreprocessContrast, kPreprocessBinarize}, job.applied)
+		gray, ok := job.img.(*image.Gray)
+		require.True(t, ok)
+		for _, v := range gray.Pix {
+			assert.Contains(t, []uint8{0, 255}, v)
+		}
+		assert.Equal(t, uint8(0), gray.GrayAt(32, 15).Y)
+		assert.Equal(t, uint8(255), gray.GrayAt(5, 5).Y)
+		assert.Equal(t, uint8(255), gray.GrayAt(60, 2).Y)
+	})
+
+	t.Run("denoise flat image", func(t *testing.T) {
+		// 纯色图片滤波后没有变化，不报告denoise
+		gray := grayPage(20, 10, 200)
+		job := preprocessDecoded(gray, 0, []string{kPreprocessDenoise})
+		assert.Empty(t, job.applied)
+		assert.Same(t, gray, job.img)
+
+		job = preprocessDecoded(image.NewNRGBA(image.Rect(0, 0, 20, 10)), 0, []string{kPreprocessDenoise})
+		assert.NotContains(t, job.applied, kPreprocessDenoise)
+	})
+
+	t.Run("contrast", func(t *testing.T) {
+		job := &preprocessJob{img: image.NewGray(image.Rect(0, 0, 10, 10)), scale: 1}
+		for i := range job.img.(*image.Gray).Pix {
+			job.img.(*image.Gray).Pix[i] = uint8(100 + i/2)
+		}
+		require.True(t, stretchContrast(job))
+		gray := job.img.(*image.Gray)
+		assert.Equal(t, uint8(0), gray.Pix[0])
+		assert.Equal(t, uint8(255), gray.Pix[99])
+
+		// 已经占满0~255的图片不处理
+		job.img.(*image.Gray).Pix[0], job.img.(*image.Gray).Pix[1] = 0, 0
+		assert.False(t, stretchContrast(job))
+	})
+
+	t.Run("clahe", func(t *testing.T) {
+		src := image.NewGray(image.Rect(0, 0, 50, 30))
+		for i := range src.Pix {
+			src.Pix[i] = uint8(100 + i%10)
+		}
+		job := &preprocessJob{img: src, scale: 1}
+		require.True(t, equalizeCLAHE(job))
+		gray := job.img.(*image.Gray)
+		assert.Equal(t, src.Rect, gray.Rect)
+		// 低对比度区域被拉开
+		assert.Greater(t, int(gray.GrayAt(9, 0).Y)-int(gray.GrayAt(0, 0).Y), 9)
+	})
+
+	t.Run("input unchanged", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+		for i := range src.Pix {
+			src.Pix[i] = uint8(100 + i%40)
+		}
+		original := append([]uint8(nil), src.Pix...)
+		job := preprocessDecoded(src, 0, []string{kPreprocessFlatten, kPreprocessContrast})
+		assert.Equal(t, []string{kPreprocessFlatten, kPreprocessContrast}, job.applied)
+		assert.Equal(t, original, src.Pix)
+	})
+
+	t.Run("undecodable", func(t *testing.T) {
+		_, err := preprocessImage([]byte("not an image"), []string{kPreprocessGrayscale})
+		assert.ErrorIs(t, err, ErrImageDecode)
+	})
+}
+
+func TestScaleResultBoxes(t *testing.T) {
+	blocks := []OCRTextBlock{{
+		BoxPoint:  []OCRBoxPoint{{X: 40, Y: 8}, {X: 79, Y: 9}},
+		CharBoxes: []OCRCharBox{{BoxPoint: []OCRBoxPoint{{X: 44, Y: 12}}}},
+	}}
+	scaleResultBoxes(blocks, 4)
+	// 四舍五入，不截断
+	assert.Equal(t, []OCRBoxPoint{{X: 10, Y: 2}, {X: 20, Y: 2}}, blocks[0].BoxPoint)
+	assert.Equal(t, []OCRBoxPoint{{X: 11, Y: 3}}, blocks[0].CharBoxes[0].BoxPoint)
+}
+
+func TestScaleResultImage(t *testing.T) {
+	data, err := scaleResultImage(fakeResultImage(kImageFormatJPEG), kImageFormatJPEG, image.Pt(30, 10))
+	require.NoError(t, err)
+	img, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, kImageFormatJPEG, format)
+	assert.Equal(t, image.Rect(0, 0, 30, 10), img.Bounds())
+
+	_, err = scaleResultImage([]byte("not an image"), kImageFormatPNG, image.Pt(30, 10))
+	assert.ErrorIs(t, err, ErrImageDecode)
+}
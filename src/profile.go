@@ -0,0 +1,168 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// kDefaultProfile 未配置模型配置文件时唯一的模型配置名称
+const kDefaultProfile = "default"
+
+// Profile 一套命名的模型及其默认检测参数
+type Profile struct {
+	Name   string
+	Engine Engine
+	// Detect 请求未指定时使用的检测参数
+	Detect DetectParams
+}
+
+// ProfileInfo 模型配置的描述信息
+type ProfileInfo struct {
+	Name    string       `json:"name"`
+	Default bool         `json:"default,omitempty"`
+	Detect  DetectParams `json:"detect"`
+	Engine  EngineInfo   `json:"engine"`
+}
+
+// ProfileSet 同时加载的多套模型配置，请求通过名称选择
+type ProfileSet struct {
+	profiles    map[string]*Profile
+	defaultName string
+}
+
+// NewProfileSet 创建模型配置集合，defaultName为请求未指定时使用的配置
+func NewProfileSet(defaultName string, profiles ...*Profile) (*ProfileSet, error) {
+	set := &ProfileSet{profiles: make(map[string]*Profile, len(profiles)), defaultName: defaultName}
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("模型配置名称不能为空")
+		}
+		if _, ok := set.profiles[profile.Name]; ok {
+			return nil, fmt.Errorf("模型配置重复: %s", profile.Name)
+		}
+		set.profiles[profile.Name] = profile
+	}
+	if _, ok := set.profiles[defaultName]; !ok {
+		return nil, fmt.Errorf("默认模型配置不存在: %s", defaultName)
+	}
+	return set, nil
+}
+
+// Get 返回指定名称的模型配置，name为空时返回默认配置
+func (s *ProfileSet) Get(name string) (*Profile, error) {
+	if name == "" {
+		name = s.defaultName
+	}
+	profile, ok := s.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: 未知的模型配置%s，可选值: %s", ErrInvalidArgument, name, strings.Join(s.Names(), ", "))
+	}
+	return profile, nil
+}
+
+// Default 返回默认模型配置
+func (s *ProfileSet) Default() *Profile {
+	return s.profiles[s.defaultName]
+}
+
+// Names 返回所有模型配置名称
+func (s *ProfileSet) Names() []string {
+	names := make([]string, 0, len(s.profiles))
+	for name := range s.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Info 返回所有模型配置的描述信息
+func (s *ProfileSet) Info() []ProfileInfo {
+	infos := make([]ProfileInfo, 0, len(s.profiles))
+	for _, name := range s.Names() {
+		profile := s.profiles[name]
+		infos = append(infos, ProfileInfo{
+			Name:    name,
+			Default: name == s.defaultName,
+			Detect:  profile.Detect,
+			Engine:  profile.Engine.Info(),
+		})
+	}
+	return infos
+}
+
+// Close 关闭所有模型配置的引擎
+func (s *ProfileSet) Close() error {
+	var err error
+	for _, name := range s.Names() {
+		if closeErr := s.profiles[name].Engine.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// ProfileConfig 模型配置文件中的单个配置
+type ProfileConfig struct {
+	Name string `json:"name"`
+	// Manifest 模型清单路径，相对路径相对于模型配置文件所在目录
+	Manifest string `json:"manifest"`
+	// Detect 在服务默认检测参数基础上调整的参数
+	Detect DetectOptions `json:"detect"`
+}
+
+// profilesFile 模型配置文件
+type profilesFile struct {
+	Default  string          `json:"default"`
+	Profiles []ProfileConfig `json:"profiles"`
+}
+
+// LoadProfileConfigs 按配置返回所有模型配置及默认配置名称
+//
+// 未配置模型配置文件时返回唯一的default配置，使用config中的模型清单和检测参数。
+func LoadProfileConfigs(config Config) (string, []ProfileConfig, error) {
+	if config.Profiles == "" {
+		return kDefaultProfile, []ProfileConfig{{Name: kDefaultProfile, Manifest: config.ModelManifest}}, nil
+	}
+
+	data, err := os.ReadFile(config.Profiles)
+	if err != nil {
+		return "", nil, fmt.Errorf("读取模型配置文件失败: %w", err)
+	}
+	var file profilesFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return "", nil, fmt.Errorf("解析模型配置文件%s失败: %w", config.Profiles, err)
+	}
+	if len(file.Profiles) == 0 {
+		return "", nil, fmt.Errorf("模型配置文件%s中没有模型配置", config.Profiles)
+	}
+
+	dir := filepath.Dir(config.Profiles)
+	names := make(map[string]bool, len(file.Profiles))
+	for i := range file.Profiles {
+		profile := &file.Profiles[i]
+		if profile.Name == "" {
+			return "", nil, fmt.Errorf("模型配置文件%s中第%d个模型配置的名称为空", config.Profiles, i+1)
+		}
+		if names[profile.Name] {
+			return "", nil, fmt.Errorf("模型配置文件%s中模型配置重复: %s", config.Profiles, profile.Name)
+		}
+		names[profile.Name] = true
+		if profile.Manifest == "" {
+			return "", nil, fmt.Errorf("模型配置文件%s中模型配置%s未设置manifest", config.Profiles, profile.Name)
+		}
+		if !filepath.IsAbs(profile.Manifest) {
+			profile.Manifest = filepath.Join(dir, profile.Manifest)
+		}
+	}
+	if file.Default == "" {
+		file.Default = file.Profiles[0].Name
+	}
+	return file.Default, file.Profiles, nil
+}
@@ -0,0 +1,110 @@
+package src
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileSet(t *testing.T) {
+	general := &Profile{Name: "general", Engine: NewFakeEngine(), Detect: DefaultDetectParams()}
+	digits := &Profile{Name: "digits", Engine: NewFakeEngine(), Detect: DefaultDetectParams()}
+
+	profiles, err := NewProfileSet("general", general, digits)
+	require.NoError(t, err)
+
+	profile, err := profiles.Get("")
+	assert.NoError(t, err)
+	assert.Same(t, general, profile)
+	profile, err = profiles.Get("digits")
+	assert.NoError(t, err)
+	assert.Same(t, digits, profile)
+
+	_, err = profiles.Get("english")
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	assert.Contains(t, err.Error(), "digits, general")
+
+	infos := profiles.Info()
+	require.Len(t, infos, 2)
+	assert.Equal(t, "digits", infos[0].Name)
+	assert.False(t, infos[0].Default)
+	assert.True(t, infos[1].Default)
+	assert.Equal(t, "fake", infos[1].Engine.Name)
+
+	assert.NoError(t, profiles.Close())
+	_, err = general.Engine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrEngineClosed)
+}
+
+func TestNewProfileSetErrors(t *testing.T) {
+	_, err := NewProfileSet("general", &Profile{Name: "digits", Engine: NewFakeEngine()})
+	assert.Error(t, err)
+
+	_, err = NewProfileSet("general", &Profile{Name: "general", Engine: NewFakeEngine()}, &Profile{Name: "general", Engine: NewFakeEngine()})
+	assert.Error(t, err)
+}
+
+func TestLoadProfileConfigs(t *testing.T) {
+	t.Run("single default profile", func(t *testing.T) {
+		config := DefaultConfig()
+		name, profiles, err := LoadProfileConfigs(config)
+		require.NoError(t, err)
+		assert.Equal(t, "default", name)
+		assert.Equal(t, []ProfileConfig{{Name: "default", Manifest: kDefaultModelManifest}}, profiles)
+	})
+
+	t.Run("profiles file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "profiles.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{
+			"profiles": [
+				{"name": "general", "manifest": "general/manifest.json"},
+				{"name": "digits", "manifest": "/models/digits.json", "detect": {"preset": "fast", "padding": 10}}
+			]
+		}`), 0644))
+
+		config := DefaultConfig()
+		config.Profiles = path
+		name, profiles, err := LoadProfileConfigs(config)
+		require.NoError(t, err)
+		assert.Equal(t, "general", name)
+		require.Len(t, profiles, 2)
+		assert.Equal(t, filepath.Join(dir, "general/manifest.json"), profiles[0].Manifest)
+		assert.Equal(t, "/models/digits.json", profiles[1].Manifest)
+
+		detect, err := profiles[1].Detect.Resolve(config.Detect)
+		require.NoError(t, err)
+		assert.Equal(t, 10, detect.Padding)
+		assert.Equal(t, 640, detect.MaxSideLen)
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			content  string
+			contains string
+		}{
+			{"no profiles", `{"profiles": []}`, "没有模型配置"},
+			{"empty name", `{"profiles": [{"manifest": "a.json"}]}`, "第1个模型配置的名称为空"},
+			{"empty manifest", `{"profiles": [{"name": "general", "manifest": "a.json"}, {"name": "digits"}]}`, "模型配置digits未设置manifest"},
+			{"duplicate name", `{"profiles": [{"name": "general", "manifest": "a.json"}, {"name": "general", "manifest": "b.json"}]}`, "模型配置重复: general"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "profiles.json")
+				require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+				config := DefaultConfig()
+				config.Profiles = path
+				_, _, err := LoadProfileConfigs(config)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.contains)
+			})
+		}
+	})
+}
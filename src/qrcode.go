@@ -3,8 +3,7 @@ package src
 import (
 	"fmt"
 	"image"
-	_ "image/jpeg"
-	_ "image/png"
+	"io"
 	"log"
 	"os"
 
@@ -53,15 +52,29 @@ func detectQRCode(imagePath string) *QRCodeResult {
 		}
 	}()
 
-	// 解码图片
-	img, format, err := image.Decode(file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("二维码识别: 读取图片文件失败: %v", err)
+		return &QRCodeResult{Found: false}
+	}
+	return DetectQRCodeFromBytes(data)
+}
+
+// DetectQRCodeFromBytes 检测内存中已编码图片的二维码，图片按EXIF方向旋转后识别，与OCR看到的方向一致
+func DetectQRCodeFromBytes(data []byte) *QRCodeResult {
+	img, orientation, err := decodeOrientedImage(data)
 	if err != nil {
 		log.Printf("二维码识别: 解码图片失败: %v", err)
 		return &QRCodeResult{Found: false}
 	}
 
-	log.Printf("二维码识别: 成功解码图片格式: %s", format)
+	log.Printf("二维码识别: 成功解码图片，EXIF方向: %d", orientation)
+
+	return DetectQRCodeFromImage(img)
+}
 
+// DetectQRCodeFromImage 检测已解码图片的二维码
+func DetectQRCodeFromImage(img image.Image) *QRCodeResult {
 	// 创建bitmap
 	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
 	if err != nil {
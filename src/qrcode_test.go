@@ -1,9 +1,14 @@
 package src
 
 import (
+	"bytes"
+	"image"
+	"image/png"
 	"os"
 	"testing"
 
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -54,6 +59,42 @@ func TestDetectQRCode(t *testing.T) {
 	})
 }
 
+func TestDetectQRCodeFromBytes(t *testing.T) {
+	t.Run("png with qr code", func(t *testing.T) {
+		matrix, err := qrcode.NewQRCodeWriter().Encode("https://example.com", gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, matrix))
+
+		result := DetectQRCodeFromBytes(buf.Bytes())
+		assert.True(t, result.Found)
+		assert.Equal(t, "https://example.com", result.Content)
+	})
+
+	t.Run("mirrored jpeg with exif orientation", func(t *testing.T) {
+		matrix, err := qrcode.NewQRCodeWriter().Encode("https://example.com", gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+		assert.NoError(t, err)
+		mirrored := image.NewGray(image.Rect(0, 0, 200, 200))
+		for y := 0; y < 200; y++ {
+			for x := 0; x < 200; x++ {
+				mirrored.Set(199-x, y, matrix.At(x, y))
+			}
+		}
+
+		// 带EXIF方向的JPEG按翻转后的图片识别
+		result := DetectQRCodeFromBytes(jpegWithOrientation(t, mirrored, 2))
+		assert.True(t, result.Found)
+		assert.Equal(t, "https://example.com", result.Content)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		result := DetectQRCodeFromBytes([]byte("not an image"))
+		assert.False(t, result.Found)
+		assert.Empty(t, result.Content)
+	})
+}
+
 func TestValidateImageForQRCode(t *testing.T) {
 	// 测试空路径
 	t.Run("empty path", func(t *testing.T) {
@@ -0,0 +1,227 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrReloadInProgress 已有重新加载正在进行
+var ErrReloadInProgress = errors.New("模型正在重新加载，请稍后重试")
+
+// EngineLoader 按模型清单路径创建新引擎
+type EngineLoader func(manifest string) (Engine, error)
+
+// engineGeneration 一次加载得到的引擎，记录正在使用它的请求数
+type engineGeneration struct {
+	engine   Engine
+	manifest string
+	inflight sync.WaitGroup
+}
+
+// ReloadableEngine 支持在运行时替换模型的引擎
+//
+// 重新加载时先创建并预热新引擎，成功后新请求切换到新引擎，
+// 已经开始的请求继续在旧引擎上完成，之后再释放旧引擎。
+// 新模型加载或预热失败时保持使用原引擎。
+type ReloadableEngine struct {
+	loader EngineLoader
+	// detect 预热新引擎时使用的检测参数
+	detect DetectParams
+	// manifestDir 初始模型清单所在的目录，重新加载只允许使用该目录下的清单
+	manifestDir string
+	reloadMu    sync.Mutex
+
+	mu      sync.RWMutex
+	current *engineGeneration
+	closed  bool
+}
+
+// NewReloadableEngine 使用初始引擎创建可重新加载的引擎，manifest为初始引擎使用的模型清单，
+// detect为使用该引擎的模型配置的检测参数，用于预热新引擎
+func NewReloadableEngine(engine Engine, manifest string, detect DetectParams, loader EngineLoader) *ReloadableEngine {
+	return &ReloadableEngine{
+		loader:      loader,
+		detect:      detect,
+		manifestDir: filepath.Dir(manifest),
+		current:     &engineGeneration{engine: engine, manifest: manifest},
+	}
+}
+
+func (e *ReloadableEngine) RecognizeBytes(ctx context.Context, data []byte, params DetectParams) (*OCRResultData, error) {
+	generation, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer generation.inflight.Done()
+
+	return generation.engine.RecognizeBytes(ctx, data, params)
+}
+
+func (e *ReloadableEngine) RecognizeImage(ctx context.Context, img image.Image, params DetectParams) (*OCRResultData, error) {
+	generation, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer generation.inflight.Done()
+
+	return generation.engine.RecognizeImage(ctx, img, params)
+}
+
+func (e *ReloadableEngine) RecognizeLines(ctx context.Context, lines []image.Image, params DetectParams) (*OCRResultData, error) {
+	generation, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer generation.inflight.Done()
+
+	return generation.engine.RecognizeLines(ctx, lines, params)
+}
+
+func (e *ReloadableEngine) DetectBytes(ctx context.Context, data []byte, params DetectParams) (*DetectResultData, error) {
+	generation, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer generation.inflight.Done()
+
+	return generation.engine.DetectBytes(ctx, data, params)
+}
+
+func (e *ReloadableEngine) DetectImage(ctx context.Context, img image.Image, params DetectParams) (*DetectResultData, error) {
+	generation, err := e.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer generation.inflight.Done()
+
+	return generation.engine.DetectImage(ctx, img, params)
+}
+
+// Reload 加载manifest中的模型并替换当前引擎，manifest为空时重新加载当前清单
+//
+// manifest必须位于初始模型清单所在的目录中。
+func (e *ReloadableEngine) Reload(ctx context.Context, manifest string) error {
+	if manifest != "" {
+		if err := e.checkManifest(manifest); err != nil {
+			return err
+		}
+	}
+
+	old, err := e.swap(ctx, manifest)
+	if err != nil {
+		return err
+	}
+
+	// 等待旧引擎上的请求完成后释放，等待期间允许再次重新加载
+	old.inflight.Wait()
+	if err := old.engine.Close(); err != nil {
+		log.Printf("释放旧引擎失败: %v", err)
+	}
+	return nil
+}
+
+// swap 加载并预热新引擎后替换当前引擎，返回被替换的引擎
+func (e *ReloadableEngine) swap(ctx context.Context, manifest string) (*engineGeneration, error) {
+	if !e.reloadMu.TryLock() {
+		return nil, ErrReloadInProgress
+	}
+	defer e.reloadMu.Unlock()
+
+	e.mu.RLock()
+	closed := e.closed
+	if manifest == "" {
+		manifest = e.current.manifest
+	}
+	e.mu.RUnlock()
+	if closed {
+		return nil, ErrEngineClosed
+	}
+
+	log.Printf("正在重新加载模型，清单: %s", manifest)
+	engine, err := e.loader(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("加载新模型失败，继续使用原模型: %w", err)
+	}
+	if err := warmUp(ctx, engine, e.detect); err != nil {
+		engine.Close()
+		return nil, fmt.Errorf("新模型预热失败，继续使用原模型: %w", err)
+	}
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		engine.Close()
+		return nil, ErrEngineClosed
+	}
+	old := e.current
+	e.current = &engineGeneration{engine: engine, manifest: manifest}
+	e.mu.Unlock()
+	log.Printf("已切换到新模型，引擎: %s", engine.Info().Name)
+	return old, nil
+}
+
+// checkManifest 检查manifest位于初始模型清单所在的目录中
+func (e *ReloadableEngine) checkManifest(manifest string) error {
+	dir, err := filepath.Abs(e.manifestDir)
+	if err == nil {
+		var path string
+		if path, err = filepath.Abs(manifest); err == nil {
+			var rel string
+			rel, err = filepath.Rel(dir, path)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: 模型清单必须位于目录%s中: %s", ErrInvalidArgument, e.manifestDir, manifest)
+}
+
+// Close 等待进行中的请求完成后关闭当前引擎
+func (e *ReloadableEngine) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	current := e.current
+	e.mu.Unlock()
+
+	current.inflight.Wait()
+	return current.engine.Close()
+}
+
+func (e *ReloadableEngine) Info() EngineInfo {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.current.engine.Info()
+}
+
+// acquire 获取当前引擎并登记一个进行中的请求，使用后必须调用inflight.Done
+func (e *ReloadableEngine) acquire() (*engineGeneration, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return nil, ErrEngineClosed
+	}
+	e.current.inflight.Add(1)
+	return e.current, nil
+}
+
+// warmUp 使用一张合成图片按params执行一次识别，确保模型可以正常推理
+func warmUp(ctx context.Context, engine Engine, params DetectParams) error {
+	img := image.NewGray(image.Rect(0, 0, 160, 48))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(16, 16, 144, 32), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	_, err := engine.RecognizeImage(ctx, img, params)
+	return err
+}
@@ -0,0 +1,158 @@
+package src
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func currentEngine(e *ReloadableEngine) Engine {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.current.engine
+}
+
+func TestReloadableEngineReload(t *testing.T) {
+	old := NewFakeEngine()
+	next := NewFakeEngine()
+	detect := DefaultDetectParams()
+	detect.MaxSideLen = 640
+	var loaded []string
+	engine := NewReloadableEngine(old, "models/v1.json", detect, func(manifest string) (Engine, error) {
+		loaded = append(loaded, manifest)
+		return next, nil
+	})
+
+	require.NoError(t, engine.Reload(context.Background(), ""))
+	assert.Equal(t, []string{"models/v1.json"}, loaded)
+	assert.Equal(t, Engine(next), currentEngine(engine))
+	// 预热使用模型配置的检测参数
+	assert.Equal(t, detect, next.LastParams())
+	// 预热执行了一次识别
+	assert.Equal(t, 1, next.Calls())
+
+	_, err := engine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next.Calls())
+	assert.Equal(t, 0, old.Calls())
+
+	// 旧引擎已释放
+	_, err = old.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrEngineClosed)
+
+	require.NoError(t, engine.Reload(context.Background(), "models/v2.json"))
+	assert.Equal(t, []string{"models/v1.json", "models/v2.json"}, loaded)
+}
+
+func TestReloadableEngineDrainsInflight(t *testing.T) {
+	var active, maxSeen int32
+	old := &blockingEngine{started: make(chan struct{}, 1), release: make(chan struct{}), active: &active, maxSeen: &maxSeen}
+	next := NewFakeEngine()
+	loads := 0
+	engine := NewReloadableEngine(old, "", DefaultDetectParams(), func(manifest string) (Engine, error) {
+		loads++
+		if loads > 1 {
+			return NewFakeEngine(), nil
+		}
+		return next, nil
+	})
+
+	inflight := make(chan error)
+	go func() {
+		_, err := engine.RecognizeBytes(context.Background(), []byte("slow"), DefaultDetectParams())
+		inflight <- err
+	}()
+	<-old.started
+
+	reloaded := make(chan error)
+	go func() {
+		reloaded <- engine.Reload(context.Background(), "")
+	}()
+
+	// 新请求切换到新引擎，旧引擎在请求完成前不会被释放
+	assert.Eventually(t, func() bool { return currentEngine(engine) == Engine(next) }, time.Second, time.Millisecond)
+	_, err := engine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.NoError(t, err)
+	select {
+	case <-reloaded:
+		t.Fatal("旧引擎上的请求尚未完成")
+	case <-time.After(10 * time.Millisecond):
+	}
+	// 等待旧引擎释放期间可以再次重新加载
+	require.NoError(t, engine.Reload(context.Background(), ""))
+	assert.NotEqual(t, Engine(next), currentEngine(engine))
+
+	close(old.release)
+	assert.NoError(t, <-inflight)
+	assert.NoError(t, <-reloaded)
+	_, err = old.FakeEngine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrEngineClosed)
+}
+
+func TestReloadableEngineRollback(t *testing.T) {
+	t.Run("load failure", func(t *testing.T) {
+		old := NewFakeEngine()
+		engine := NewReloadableEngine(old, "", DefaultDetectParams(), func(manifest string) (Engine, error) {
+			return nil, errors.New("crnn模型文件不存在")
+		})
+
+		err := engine.Reload(context.Background(), "")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "crnn模型文件不存在")
+
+		_, err = engine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, old.Calls())
+	})
+
+	t.Run("warm up failure", func(t *testing.T) {
+		old := NewFakeEngine()
+		next := &FakeEngine{Err: ErrInference}
+		engine := NewReloadableEngine(old, "", DefaultDetectParams(), func(manifest string) (Engine, error) {
+			return next, nil
+		})
+
+		err := engine.Reload(context.Background(), "")
+		assert.ErrorIs(t, err, ErrInference)
+		assert.Equal(t, Engine(old), currentEngine(engine))
+
+		// 预热失败的新引擎已释放
+		_, err = next.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+		assert.ErrorIs(t, err, ErrEngineClosed)
+	})
+}
+
+func TestReloadableEngineManifestDir(t *testing.T) {
+	var loaded []string
+	engine := NewReloadableEngine(NewFakeEngine(), "models/manifest.json", DefaultDetectParams(), func(manifest string) (Engine, error) {
+		loaded = append(loaded, manifest)
+		return NewFakeEngine(), nil
+	})
+
+	require.NoError(t, engine.Reload(context.Background(), "models/v2/manifest.json"))
+	require.NoError(t, engine.Reload(context.Background(), "./models/../models/v3.json"))
+	for _, manifest := range []string{"/etc/manifest.json", "../models/manifest.json", "models/../manifest.json", "models2/manifest.json"} {
+		assert.ErrorIs(t, engine.Reload(context.Background(), manifest), ErrInvalidArgument, manifest)
+	}
+	assert.Equal(t, []string{"models/v2/manifest.json", "./models/../models/v3.json"}, loaded)
+}
+
+func TestReloadableEngineClose(t *testing.T) {
+	old := NewFakeEngine()
+	engine := NewReloadableEngine(old, "", DefaultDetectParams(), func(manifest string) (Engine, error) {
+		return NewFakeEngine(), nil
+	})
+
+	assert.NoError(t, engine.Close())
+	assert.NoError(t, engine.Close())
+	assert.ErrorIs(t, engine.Reload(context.Background(), ""), ErrEngineClosed)
+
+	_, err := engine.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrEngineClosed)
+	_, err = old.RecognizeBytes(context.Background(), []byte("png"), DefaultDetectParams())
+	assert.ErrorIs(t, err, ErrEngineClosed)
+}
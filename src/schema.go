@@ -0,0 +1,53 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ResultSchemaVersion 识别结果的结构版本，必须与cpp/include/ocr.h中的kOcrResultSchemaVersion一致
+const ResultSchemaVersion = 7
+
+// ParamsVersion 检测参数的结构版本，必须与cpp/include/ocr.h中的kOcrParamsVersion一致，
+// toCDetectParams按该版本的OcrDetectParams布局填写参数
+const ParamsVersion = 6
+
+// ErrSchemaMismatch C侧识别结果的结构与Go侧不一致
+var ErrSchemaMismatch = errors.New("识别结果结构版本不匹配")
+
+// DecodeResult 解析ocr_detect输出的json识别结果
+//
+// 结果的schema_version必须等于ResultSchemaVersion，并且不允许出现未知字段，
+// 避免两侧字段命名不一致时数据被静默丢弃。
+func DecodeResult(data []byte) (*OCRResultData, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var result OCRResultData
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSchemaMismatch, err)
+	}
+	if err := checkSchemaVersion(result.SchemaVersion); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// checkSchemaVersion 检查C侧结果的结构版本
+func checkSchemaVersion(version int) error {
+	if version != ResultSchemaVersion {
+		return fmt.Errorf("%w: 期望版本%d，实际版本%d", ErrSchemaMismatch, ResultSchemaVersion, version)
+	}
+	return nil
+}
+
+// checkParamsVersion 检查C侧检测参数的结构版本，版本不一致时按Go侧的布局传入的参数会被错误解读
+func checkParamsVersion(version int) error {
+	if version != ParamsVersion {
+		return fmt.Errorf("%w: 检测参数期望版本%d，实际版本%d", ErrSchemaMismatch, ParamsVersion, version)
+	}
+	return nil
+}
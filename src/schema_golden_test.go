@@ -0,0 +1,81 @@
+//go:build !test
+// +build !test
+
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden 为true时用OcrLiteOnnx的实际输出重新生成testdata中的结果基准文件
+//
+//	go test ./src -run TestGoldenResult -update-golden
+var updateGolden = flag.Bool("update-golden", false, "用实际识别结果重新生成testdata/ocr_result_v<版本>.json")
+
+const (
+	kGoldenManifest = "../models/manifest.json"
+	kGoldenImage    = "../cpp/images/1.jpg"
+)
+
+// TestGoldenResult 用真实模型识别示例图片，检查C侧toJson的输出与基准文件的字段一致
+func TestGoldenResult(t *testing.T) {
+	models, err := LoadModelManifest(kGoldenManifest)
+	if err != nil {
+		t.Skipf("模型文件不可用: %v", err)
+	}
+	// 仓库中不包含crnn等模型，缺少时跳过而不是在创建实例时失败
+	if err := models.Validate(); err != nil {
+		t.Skipf("模型文件不可用: %v", err)
+	}
+	engine, err := newOcrLiteInstance(1, models)
+	require.NoError(t, err)
+	defer engine.Close()
+
+	data, err := engine.detectFileJSON(context.Background(), kGoldenImage)
+	require.NoError(t, err)
+	result, err := DecodeResult(data)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.TextBlocks, "示例图片应识别出文本")
+
+	path := goldenResultPath()
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	require.NoError(t, err, "结构版本变化后需要使用-update-golden生成新的基准文件")
+	assert.Equal(t, jsonKeys(t, golden), jsonKeys(t, data))
+}
+
+// jsonKeys 返回json中出现的所有字段路径，数组元素的字段合并到同一路径下
+func jsonKeys(t *testing.T, data []byte) map[string]bool {
+	t.Helper()
+	var value interface{}
+	require.NoError(t, json.Unmarshal(data, &value))
+
+	keys := make(map[string]bool)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				keys[prefix+key] = true
+				walk(prefix+key+".", child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(prefix, child)
+			}
+		}
+	}
+	walk("", value)
+	return keys
+}
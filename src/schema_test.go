@@ -0,0 +1,92 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// goldenResultPath 返回当前结构版本的结果基准文件，文件内容是ocr_detect_handle的原始输出，
+// 递增kOcrResultSchemaVersion后在能运行OcrLiteOnnx的环境中执行
+//
+//	go test ./src -run TestGoldenResult -update-golden
+//
+// 重新生成，见schema_golden_test.go。
+func goldenResultPath() string {
+	return fmt.Sprintf("testdata/ocr_result_v%d.json", ResultSchemaVersion)
+}
+
+func TestDecodeResultFixture(t *testing.T) {
+	data, err := os.ReadFile(goldenResultPath())
+	require.NoError(t, err)
+
+	result, err := DecodeResult(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, ResultSchemaVersion, result.SchemaVersion)
+	assert.Greater(t, result.DBNetTime, 0.0)
+	assert.Greater(t, result.DetectTime, 0.0)
+	require.NotEmpty(t, result.TextBlocks)
+	require.Len(t, result.Texts, len(result.TextBlocks))
+
+	for i, block := range result.TextBlocks {
+		assert.Equal(t, result.Texts[i], block.Text)
+		assert.Len(t, block.BoxPoint, 4)
+		assert.Greater(t, block.BoxScore, 0.0)
+		assert.Greater(t, block.CRNNTime, 0.0)
+		assert.Equal(t, utf8.RuneCountInString(block.Text), len(block.CharScores))
+	}
+}
+
+func TestDecodeResultRoundTrip(t *testing.T) {
+	data, err := os.ReadFile(goldenResultPath())
+	require.NoError(t, err)
+
+	result, err := DecodeResult(data)
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(result)
+	require.NoError(t, err)
+
+	// C侧输出的每个字段都必须被Go侧完整保留
+	var expected, actual map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &expected))
+	require.NoError(t, json.Unmarshal(encoded, &actual))
+	assert.Equal(t, expected, actual)
+}
+
+func TestDecodeResultRejectsMismatch(t *testing.T) {
+	t.Run("legacy camelCase keys", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/ocr_result_legacy.json")
+		require.NoError(t, err)
+
+		_, err = DecodeResult(data)
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+	})
+
+	t.Run("missing schema version", func(t *testing.T) {
+		_, err := DecodeResult([]byte(`{"texts":["a"]}`))
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+	})
+
+	t.Run("newer schema version", func(t *testing.T) {
+		_, err := DecodeResult([]byte(fmt.Sprintf(`{"schema_version":%d,"texts":["a"]}`, ResultSchemaVersion+1)))
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := DecodeResult([]byte(`{"schema_version":`))
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+	})
+}
+
+func TestCheckParamsVersion(t *testing.T) {
+	assert.NoError(t, checkParamsVersion(ParamsVersion))
+	assert.ErrorIs(t, checkParamsVersion(ParamsVersion-1), ErrSchemaMismatch)
+	assert.ErrorIs(t, checkParamsVersion(ParamsVersion+1), ErrSchemaMismatch)
+}